@@ -0,0 +1,35 @@
+package main
+
+import (
+	"gocv.io/x/gocv"
+	"gocv.io/x/gocv/contrib"
+)
+
+var calibrateCommand = command{
+	name:  "calibrate",
+	usage: "calibrate the camera with a 12x9 ChArUco board",
+	run:   runCalibrate,
+}
+
+func runCalibrate(o *options, args []string) error {
+	window, err := o.display.Open("Calibrate")
+	if err != nil {
+		return err
+	}
+	defer window.Close()
+
+	keys, err := o.keyBindings()
+	if err != nil {
+		return err
+	}
+	d, err := o.newDrone(keys)
+	if err != nil {
+		return err
+	}
+
+	go contrib.CalibrateCameraChArUco(12, 9, 0.30, 0.26)
+	return showVideo(d, window, func(frame *gocv.Mat) bool {
+		contrib.SetImg(frame)
+		return true
+	})
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"flag"
+	"image"
+	"image/color"
+
+	"gocv.io/x/gocv"
+	"tellobot/gesture"
+)
+
+var gesturesCommand = command{
+	name:  "gestures",
+	usage: "fly with body gestures: palm hovers, arms up lands, pointing turns",
+	flags: func(fs *flag.FlagSet, o *options) {
+		fs.Var(settingFlag{"gesture.model", o}, "model", "pose model, onnx")
+		fs.Var(settingFlag{"gesture.backend", o}, "backend", "dnn backend")
+		fs.Var(settingFlag{"gesture.target", o}, "target", "dnn target")
+	},
+	run: runGestures,
+}
+
+func runGestures(o *options, args []string) error {
+	estimator, err := gesture.NewEstimator(o.cfg.Gesture)
+	if err != nil {
+		return err
+	}
+	defer estimator.Close()
+
+	window, err := o.display.Open("Gestures")
+	if err != nil {
+		return err
+	}
+	defer window.Close()
+
+	keys, err := o.keyBindings()
+	if err != nil {
+		return err
+	}
+	d, err := o.newDrone(keys)
+	if err != nil {
+		return err
+	}
+	p := o.newPilot(d, keys)
+	defer p.Stop()
+
+	// gestures run the same actions as the keys, through the mux like the
+	// other autopilots
+	controller := gesture.NewController(estimator, o.cfg.Gesture, keys)
+	autopilot := p.autopilot.Drone()
+	return showVideo(d, window, func(frame *gocv.Mat) bool {
+		pose, g := controller.Update(frame, autopilot)
+		for _, k := range pose {
+			if k.Confidence > 0 {
+				gocv.Circle(frame, k.Point, 4, color.RGBA{0, 255, 255, 0}, -1)
+			}
+		}
+		if g != gesture.None {
+			gocv.PutText(frame, string(g), image.Pt(10, 30), gocv.FontHersheySimplex, 0.8, color.RGBA{0, 255, 0, 0}, 2)
+		}
+		return true
+	})
+}
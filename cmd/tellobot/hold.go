@@ -0,0 +1,64 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"time"
+
+	"tellobot/tracking"
+)
+
+var holdDuration time.Duration
+
+var holdCommand = command{
+	name:  "hold",
+	usage: "take off, hold the configured altitude and land again",
+	flags: func(fs *flag.FlagSet, o *options) {
+		fs.DurationVar(&holdDuration, "duration", 15*time.Second, "how long to hold the altitude")
+	},
+	run: runHold,
+}
+
+func runHold(o *options, args []string) error {
+	keys, err := o.keyBindings()
+	if err != nil {
+		return err
+	}
+	d, err := o.newDrone(keys)
+	if err != nil {
+		return err
+	}
+
+	// the keys override the altitude hold
+	p := o.newPilot(d, keys)
+	defer p.Stop()
+	autopilot := p.autopilot.Drone()
+
+	altitude := tracking.NewAltitudeHold(o.cfg.Tracking.Altitude)
+	if err := d.TakeOff(); err != nil {
+		return err
+	}
+
+	// hold once the take off is complete
+	time.Sleep(5 * time.Second)
+	altitude.Enable()
+
+	ticker := time.NewTicker(50 * time.Millisecond)
+	defer ticker.Stop()
+	end := time.After(holdDuration)
+	for {
+		select {
+		case <-ticker.C:
+			fd := d.FlightData()
+			altitude.Update(fd, autopilot)
+			if fd != nil {
+				fmt.Printf("height %.1fm, target %.1fm\r", float32(fd.Height)/10, altitude.Target())
+			}
+		case <-end:
+			altitude.Disable()
+			p.mux.ReleaseAll()
+			fmt.Println()
+			return d.Land()
+		}
+	}
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+
+	"gocv.io/x/gocv"
+	"gocv.io/x/gocv/contrib"
+	"tellobot/drone"
+	"tellobot/tracking"
+)
+
+var landCommand = command{
+	name:  "land",
+	usage: "fly with the keys, M lands on the marker pad, again cancels",
+	run:   runLand,
+}
+
+func runLand(o *options, args []string) error {
+	window, err := o.display.Open("Land")
+	if err != nil {
+		return err
+	}
+	defer window.Close()
+
+	dict := contrib.NewArucoPredefinedDictionary(contrib.ArucoPredefinedDict_5x5_50)
+	defer dict.Close()
+
+	c := o.cfg.Tracking.Landing
+	landing := tracking.NewPrecisionLanding(c)
+	keys, err := o.keyBindings()
+	if err != nil {
+		return err
+	}
+	keys.SetAction("precision-land", func(d drone.Drone) {
+		if landing.Active() {
+			landing.Stop()
+			fmt.Println("precision landing cancelled")
+		} else {
+			landing.Start()
+			fmt.Println("precision landing")
+		}
+	})
+
+	d, err := o.newDrone(keys)
+	if err != nil {
+		return err
+	}
+	p := o.newPilot(d, keys)
+	defer p.Stop()
+
+	pad := tracking.NewArucoTarget(dict, c.MarkerID, c.MarkerSize, d)
+	return showVideo(d, window, func(frame *gocv.Mat) bool {
+		marker, ok := pad.Detect(frame)
+		if ok {
+			gocv.Rectangle(frame, marker.Box, color.RGBA{0, 255, 0, 0}, 2)
+		}
+
+		state := landing.Update(marker, ok, d.FlightData(), p.autopilot, d)
+		if !landing.Active() {
+			p.autopilot.Release()
+		}
+		gocv.PutText(frame, string(state), image.Pt(10, 30), gocv.FontHersheySimplex, 0.8, color.RGBA{0, 255, 0, 0}, 2)
+		drone.DrawControls(d, frame)
+		return true
+	})
+}
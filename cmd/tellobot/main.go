@@ -0,0 +1,364 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"image"
+	"image/color"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"gocv.io/x/gocv"
+	"tellobot/config"
+	"tellobot/display"
+	"tellobot/drone"
+	"tellobot/logging"
+	"tellobot/tracking"
+)
+
+var log = logging.Default.With("tellobot")
+
+const (
+	maxVideoErrors  = 20
+	videoRetryDelay = 50 * time.Millisecond
+)
+
+// command is a tellobot subcommand
+type command struct {
+	name  string
+	usage string
+
+	// flags registers options of this command only, may be nil
+	flags func(fs *flag.FlagSet, o *options)
+	run   func(o *options, args []string) error
+}
+
+var commands = []command{
+	raceCommand,
+	trackFaceCommand,
+	trackColorCommand,
+	trackArucoCommand,
+	gesturesCommand,
+	landCommand,
+	missionCommand,
+	holdCommand,
+	calibrateCommand,
+	viewCommand,
+	recordCommand,
+	replayCommand,
+}
+
+// options are shared by all commands
+type options struct {
+	config   string
+	settings []setting
+	display  display.Flags
+
+	// cfg is loaded once the flags are parsed
+	cfg      config.Config
+	closeLog func() error
+
+	// safety watches the drone of every command, see initDrone
+	safety *drone.Safety
+}
+
+// setting is a config value given on the command line
+type setting struct {
+	key   string
+	value string
+}
+
+// settingFlag records a flag as a setting of a fixed config key
+type settingFlag struct {
+	key string
+	o   *options
+}
+
+func (f settingFlag) String() string { return "" }
+
+func (f settingFlag) Set(value string) error {
+	f.o.settings = append(f.o.settings, setting{f.key, value})
+	return nil
+}
+
+// setFlag records key=value settings
+type setFlag struct {
+	o *options
+}
+
+func (f setFlag) String() string { return "" }
+
+func (f setFlag) Set(value string) error {
+	i := strings.Index(value, "=")
+	if i < 1 {
+		return fmt.Errorf("use key=value")
+	}
+	f.o.settings = append(f.o.settings, setting{value[:i], value[i+1:]})
+	return nil
+}
+
+// logFileFlag logs json lines into a file
+type logFileFlag struct {
+	o *options
+}
+
+func (f logFileFlag) String() string { return "" }
+
+func (f logFileFlag) Set(value string) error {
+	f.o.settings = append(f.o.settings, setting{"log.file", value}, setting{"log.format", "json"})
+	return nil
+}
+
+func (o *options) register(fs *flag.FlagSet) {
+	fs.StringVar(&o.config, "config", ".", "directory with tellobot.yaml, calibrations and tuning profiles")
+	fs.Var(settingFlag{"drone.type", o}, "drone", "drone backend, fake uses the webcam, real the tello (default fake)")
+	fs.Var(settingFlag{"drone.calibration", o}, "calibration", "camera calibration file (default depends on -drone and -width)")
+	fs.Var(settingFlag{"drone.frameWidth", o}, "width", "video frame width (default 400)")
+	fs.Var(settingFlag{"drone.frameHeight", o}, "height", "video frame height (default 300)")
+	fs.Var(settingFlag{"listen", o}, "listen", "ground control address (default localhost:8080)")
+	fs.Var(settingFlag{"log.level", o}, "log-level", "debug, info, warn or error (default info)")
+	fs.Var(logFileFlag{o}, "log", "append the log as json lines to this file")
+	fs.Var(setFlag{o}, "set", "set any config value, e.g. -set tracking.follow.distance=2, repeatable")
+	o.display.Register(fs)
+}
+
+// load layers the flags over the config file and the environment
+func (o *options) load() error {
+	cfg, err := config.Load(o.path("tellobot.yaml"))
+	if err != nil {
+		return err
+	}
+	for _, s := range o.settings {
+		if err := cfg.Set(s.key, s.value); err != nil {
+			return err
+		}
+	}
+	if err := cfg.Validate(); err != nil {
+		return err
+	}
+	if err := tracking.Configure(cfg.Tracking); err != nil {
+		return err
+	}
+	closeLog, err := logging.Open(cfg.Log)
+	if err != nil {
+		return err
+	}
+	o.closeLog = closeLog
+	// the shipped calibrations live in the configuration directory
+	if cfg.Drone.Calibration == "" {
+		cfg.Drone.Calibration = o.path(cfg.Drone.CalibrationFile())
+	}
+	o.cfg = cfg
+	return nil
+}
+
+// path returns the name of a file in the configuration directory
+func (o *options) path(name string) string {
+	return filepath.Join(o.config, name)
+}
+
+// keyBindings returns the configured key bindings
+func (o *options) keyBindings() (*drone.KeyBindings, error) {
+	return drone.NewKeyBindings(o.cfg.Drone.Keys)
+}
+
+// newDrone creates and initializes the configured drone
+func (o *options) newDrone(keys *drone.KeyBindings) (drone.Drone, error) {
+	d := drone.NewFromConfig(o.cfg.Drone, keys.Handle)
+	if err := o.initDrone(d); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+// initDrone initializes a drone and starts the safety monitor on it, which
+// stops the motors if the drone runs away
+func (o *options) initDrone(d drone.Drone) error {
+	if err := d.Init(); err != nil {
+		return fmt.Errorf("error while initializing drone: %v", err)
+	}
+	o.safety = drone.NewSafety(o.cfg.Drone.Safety, nil)
+	o.safety.Start(d)
+	return nil
+}
+
+// stopOnEmergency stops the mux once the safety monitor stopped the motors,
+// so nothing commands the drone afterwards
+func (o *options) stopOnEmergency(mux *drone.CommandMux) {
+	if o.safety == nil {
+		return
+	}
+	o.safety.On(drone.EmergencyEvent, func(data interface{}) {
+		mux.Stop()
+	})
+}
+
+// pilot lets the keys override an autopilot, both routed through a mux
+type pilot struct {
+	mux       *drone.CommandMux
+	autopilot *drone.Source
+}
+
+func (o *options) newPilot(d drone.Drone, keys *drone.KeyBindings) *pilot {
+	mux := drone.NewCommandMux(d)
+	o.stopOnEmergency(mux)
+	keys.SetSource(mux.AddSource(drone.SourceConfig{
+		Name:         "manual",
+		Priority:     drone.PriorityManual,
+		Timeout:      600 * time.Millisecond,
+		ReleaseDelay: time.Second,
+	}))
+	p := &pilot{
+		mux: mux,
+		autopilot: mux.AddSource(drone.SourceConfig{
+			Name:     "autopilot",
+			Priority: drone.PriorityAutopilot,
+			Timeout:  time.Second,
+		}),
+	}
+	mux.Start()
+	return p
+}
+
+func (p *pilot) Stop() {
+	p.mux.Stop()
+}
+
+// toggle is a flag set by key and ground control handlers and read by the
+// video loop
+type toggle struct {
+	mutex sync.Mutex
+	on    bool
+}
+
+// Toggle flips the flag and returns the new value
+func (t *toggle) Toggle() bool {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	t.on = !t.on
+	return t.on
+}
+
+func (t *toggle) Set(on bool) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	t.on = on
+}
+
+func (t *toggle) On() bool {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	return t.on
+}
+
+// Take returns the flag and clears it
+func (t *toggle) Take() bool {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	on := t.on
+	t.on = false
+	return on
+}
+
+// showVideo reads frames from the drone, hands them to fn and shows them
+// until fn returns false. It gives up once reading failed maxVideoErrors
+// times in a row, waiting a little longer after every failure.
+func showVideo(d drone.Drone, window display.Display, fn func(frame *gocv.Mat) bool) error {
+	frame := gocv.NewMat()
+	defer frame.Close()
+
+	failures := 0
+	for {
+		if err := d.ReadVideoFrame(&frame); err != nil {
+			failures++
+			log.Warn("video frame", logging.Fields{"error": err, "failures": failures})
+			if failures >= maxVideoErrors {
+				return fmt.Errorf("no video after %d attempts: %v", failures, err)
+			}
+			time.Sleep(time.Duration(failures) * videoRetryDelay)
+			continue
+		}
+		failures = 0
+		if frame.Empty() {
+			continue
+		}
+		if !fn(&frame) {
+			return nil
+		}
+		window.IMShow(frame)
+		window.WaitKey(1)
+	}
+}
+
+// drawTracks draws the tracks with their ids, the locked one in green
+func drawTracks(frame *gocv.Mat, tracks []tracking.Track, locked int) {
+	for _, t := range tracks {
+		c := color.RGBA{0, 0, 255, 0}
+		if t.ID == locked {
+			c = color.RGBA{0, 255, 0, 0}
+		}
+		if t.Missed > 0 {
+			c = color.RGBA{128, 128, 128, 0}
+		}
+		box := t.Detection.Box
+		gocv.Rectangle(frame, box, c, 2)
+		gocv.PutText(frame, strconv.Itoa(t.ID), image.Pt(box.Min.X, box.Min.Y-5), gocv.FontHersheySimplex, 0.5, c, 1)
+	}
+}
+
+// lockNext binds the next-target key to switching the locked track
+func lockNext(keys *drone.KeyBindings, tracker *tracking.MultiTracker) {
+	keys.SetAction("next-target", func(d drone.Drone) {
+		if id, ok := tracker.LockNext(); ok {
+			fmt.Println("target:", id)
+		}
+	})
+}
+
+func usage() {
+	fmt.Println("How to run:\n\ttellobot [command] [options]\n\nCommands:")
+	for _, c := range commands {
+		fmt.Printf("\t%-12s %s\n", c.name, c.usage)
+	}
+	fmt.Println("\nRun tellobot [command] -h for the options of a command.")
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+		os.Exit(2)
+	}
+
+	for _, c := range commands {
+		if c.name != os.Args[1] {
+			continue
+		}
+
+		var o options
+		fs := flag.NewFlagSet(c.name, flag.ExitOnError)
+		o.register(fs)
+		if c.flags != nil {
+			c.flags(fs, &o)
+		}
+		fs.Parse(os.Args[2:])
+
+		if err := o.load(); err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		err := c.run(&o, fs.Args())
+		o.closeLog()
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	usage()
+	os.Exit(2)
+}
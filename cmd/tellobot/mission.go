@@ -0,0 +1,112 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"time"
+
+	"gocv.io/x/gocv"
+	"tellobot/drone"
+	"tellobot/localization"
+	"tellobot/mission"
+	"tellobot/race"
+)
+
+var missionCourseFile string
+
+var missionCommand = command{
+	name:  "mission",
+	usage: "fly a mission file, O pauses and resumes, Q aborts",
+	flags: func(fs *flag.FlagSet, o *options) {
+		fs.StringVar(&missionCourseFile, "course", "", "course file with the gates of gate steps")
+	},
+	run: runMission,
+}
+
+func runMission(o *options, args []string) error {
+	if len(args) < 1 {
+		fmt.Println("How to run:\n\ttellobot mission [options] [mission file]")
+		return nil
+	}
+	m, err := mission.Load(args[0])
+	if err != nil {
+		return err
+	}
+
+	keys, err := o.keyBindings()
+	if err != nil {
+		return err
+	}
+	dronex, err := o.newDrone(keys)
+	if err != nil {
+		return err
+	}
+
+	// the mission flies as the autopilot, the keys override it
+	p := o.newPilot(dronex, keys)
+	defer p.Stop()
+
+	odometry := localization.NewOdometry(o.cfg.Race.Search.YawRate)
+	odometry.EnableFlow()
+	defer odometry.Close()
+
+	var localizer *localization.Localizer
+	if markerMap, err := localization.LoadMarkerMap(o.path("marker-map.json")); err == nil {
+		localizer = localization.NewLocalizer(markerMap)
+		defer localizer.Close()
+	}
+
+	executor := mission.NewExecutor(p.autopilot.Drone(), m, odometry)
+	keys.SetAction("pause", func(d drone.Drone) { executor.TogglePause() })
+	keys.SetAction("abort", func(d drone.Drone) { executor.Abort() })
+	if missionCourseFile != "" {
+		course, err := race.LoadCourse(missionCourseFile)
+		if err != nil {
+			return err
+		}
+		executor.SetCourse(course)
+	}
+
+	// open the window first, the drone must not take off without a way to
+	// watch and stop it
+	window, err := o.display.Open("Mission")
+	if err != nil {
+		return err
+	}
+	defer window.Close()
+
+	done := make(chan error, 1)
+	go func() {
+		done <- executor.Run()
+	}()
+
+	frame := gocv.NewMat()
+	defer frame.Close()
+
+	for {
+		select {
+		case err := <-done:
+			return err
+		default:
+		}
+
+		dronex.ReadVideoFrame(&frame)
+		if frame.Empty() {
+			time.Sleep(10 * time.Millisecond)
+			continue
+		}
+
+		odometry.UpdateFlow(&frame, dronex)
+		if localizer != nil {
+			if pose, ok := localizer.Update(&frame, dronex); ok {
+				odometry.Correct(pose)
+			}
+		}
+
+		drone.DrawCrosshair(dronex, &frame)
+		drone.DrawControls(dronex, &frame)
+
+		window.IMShow(frame)
+		window.WaitKey(1)
+	}
+}
@@ -0,0 +1,265 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"github.com/go-gl/mathgl/mgl32"
+	"gocv.io/x/gocv"
+	"net/http"
+	"tellobot/drone"
+	"tellobot/groundcontrol"
+	"tellobot/localization"
+	"tellobot/metrics"
+	"tellobot/race"
+	"tellobot/tracking"
+	"tellobot/tuning"
+	"time"
+)
+
+var courseFile string
+
+var raceCommand = command{
+	name:  "race",
+	usage: "fly through the rings, T toggles ring tracking and the search for the next one",
+	flags: func(fs *flag.FlagSet, o *options) {
+		fs.StringVar(&courseFile, "course", "", "course file, flies planned trajectories through its gates while localized")
+	},
+	run: runRace,
+}
+
+func runRace(o *options, args []string) error {
+	// create window, or none on headless machines
+	window, err := o.display.Open("Drone")
+	if err != nil {
+		return err
+	}
+	defer window.Close()
+
+	// create race
+	racex := race.NewRace(o.cfg.Race)
+	defer racex.Close()
+
+	// key bindings, T toggles ring tracking
+	var track toggle
+	keys, err := o.keyBindings()
+	if err != nil {
+		return err
+	}
+	keys.SetAction("autopilot", func(d drone.Drone) {
+		track.Toggle()
+	})
+
+	// create drone
+	dronex, err := o.newDrone(keys)
+	if err != nil {
+		return err
+	}
+
+	// dead reckoning between marker sightings
+	odometry := localization.NewOdometry(o.cfg.Race.Search.YawRate)
+	odometry.EnableFlow()
+	defer odometry.Close()
+
+	// absolute fixes are only available if the room has a marker map
+	var localizer *localization.Localizer
+	if markerMap, err := localization.LoadMarkerMap(o.path("marker-map.json")); err == nil {
+		localizer = localization.NewLocalizer(markerMap)
+		defer localizer.Close()
+	}
+
+	// approach every gate from the same height
+	altitude := tracking.NewAltitudeHold(o.cfg.Tracking.Altitude)
+	follower := tracking.NewFollower(o.cfg.Tracking.Follow)
+
+	// with a course the gates are flown along planned trajectories while
+	// the odometry has absolute fixes
+	var planner *race.Planner
+	if courseFile != "" {
+		course, err := race.LoadCourse(courseFile)
+		if err != nil {
+			return err
+		}
+		planner = race.NewPlanner(course, o.cfg.Race)
+	}
+
+	// looks for the next ring when none is visible, lands if it gives up
+	searcher, err := race.NewSearcher(o.cfg.Race.Search)
+	if err != nil {
+		return err
+	}
+
+	// the mux is the only one writing velocities to the drone: manual keys win
+	// over the altitude hold, which wins over the ring autopilot
+	mux := drone.NewCommandMux(dronex)
+	manual := mux.AddSource(drone.SourceConfig{
+		Name:         "manual",
+		Priority:     drone.PriorityManual,
+		Timeout:      600 * time.Millisecond,
+		ReleaseDelay: time.Second,
+	})
+	altitudeSource := mux.AddSource(drone.SourceConfig{
+		Name:     "altitude",
+		Priority: drone.PriorityAltitude,
+		Timeout:  time.Second,
+	})
+	autopilot := mux.AddSource(drone.SourceConfig{
+		Name:     "autopilot",
+		Priority: drone.PriorityAutopilot,
+		Timeout:  time.Second,
+	})
+	keys.SetSource(manual)
+	mux.Start()
+	defer mux.Stop()
+
+	// the safety monitor also limits the tilt, the attitude is only known
+	// once the odometry had a marker fix
+	o.stopOnEmergency(mux)
+	if o.cfg.Drone.Safety.MaxTilt > 0 {
+		o.safety.AddCondition(drone.TiltLimit(o.cfg.Drone.Safety.MaxTilt, func() (mgl32.Mat3, bool) {
+			return odometry.Pose().Rotation, odometry.Drift().Fixes > 0
+		}))
+	}
+
+	// ground control lets a second person watch and intervene from a browser
+	ground := groundcontrol.NewServer(dronex)
+	ground.SetToken(o.cfg.Token)
+	ground.SetSource(mux.AddSource(drone.SourceConfig{
+		Name:         "ground",
+		Priority:     drone.PriorityRemote,
+		Timeout:      time.Second,
+		ReleaseDelay: time.Second,
+	}))
+	ground.SetModes([]string{"manual", "track"}, "manual", func(mode string) error {
+		track.Set(mode == "track")
+		return nil
+	})
+
+	// video for phones and other headless viewers
+	rawVideo := groundcontrol.NewFrameStream()
+	defer rawVideo.Close()
+	annotatedVideo := groundcontrol.NewFrameStream()
+	defer annotatedVideo.Close()
+	ground.Handle("/video/raw", rawVideo)
+	ground.Handle("/video/annotated", annotatedVideo)
+
+	// live tuning of the controllers, profiles are kept next to the other
+	// configuration files
+	tuning.Default.SetProfileDir(o.path("profiles"))
+	ground.Handle("/tuning/", groundcontrol.Guard(o.cfg.Token, http.StripPrefix("/tuning", tuning.Default.Handler())))
+	ground.Handle("/metrics", metrics.Default.Handler())
+
+	go func() {
+		if err := ground.ListenAndServe(o.cfg.Listen); err != nil {
+			fmt.Println(err)
+		}
+	}()
+
+	// a gamepad is optional, its sticks override the autopilot like the keys
+	if gamepad, err := drone.NewGamepad(o.cfg.Drone.Gamepad, keys); err == nil {
+		gamepad.SetSource(mux.AddSource(drone.SourceConfig{
+			Name:         "gamepad",
+			Priority:     drone.PriorityManual,
+			Timeout:      time.Second,
+			ReleaseDelay: time.Second,
+		}))
+		if err := gamepad.Connect(dronex); err != nil {
+			fmt.Printf("no gamepad: %v\n", err)
+		}
+	} else {
+		fmt.Println(err)
+	}
+
+	//rings := make(map[int]*race.Ring)
+
+	return showVideo(dronex, window, func(frame *gocv.Mat) bool {
+		rawVideo.Publish(*frame)
+
+		odometry.Predict(dronex.FlightData(), dronex, time.Now())
+		odometry.UpdateFlow(frame, dronex)
+		if localizer != nil {
+			if pose, ok := localizer.Update(frame, dronex); ok {
+				odometry.Correct(pose)
+			}
+		}
+
+		rings := racex.DetectRings(frame, nil)
+		for _, ring := range rings {
+			ring.EstimatePose(dronex)
+			ring.Draw(frame, dronex)
+		}
+
+		ground.SetRings(rings)
+		ground.SetPose(odometry.Pose())
+
+		ring, found := race.Nearest(rings)
+		on := track.On()
+		if on {
+			// disabled when a search gave up and landed
+			altitude.Enable()
+		}
+		switch {
+		case on && planner != nil && odometry.Drift().Fixes > 0:
+			pose := odometry.Pose()
+			v, done := planner.Update(&pose, time.Now())
+			if done {
+				// the mux keeps writing the sources, hovering the drone
+				// directly would be overwritten with the next update
+				mux.ReleaseAll()
+				planner.Reset()
+				track.Set(false)
+				break
+			}
+			altitude.Yield(o.cfg.Tracking.Altitude.Yield)
+			autopilot.SetAxes(v, drone.AllAxes)
+		case found && on:
+			// the ring controller needs the vertical axis to line up
+			altitude.Yield(o.cfg.Tracking.Altitude.Yield)
+			detection := ring.Detection(dronex)
+			searcher.Seen(detection.Position, time.Now())
+			follower.Drive(autopilot, detection, true, frame.Cols(), frame.Rows())
+		case on:
+			v, state := searcher.Update(time.Now(), flightHeight(dronex))
+			if state == race.SearchGaveUp {
+				// the mux would keep writing velocities, including the
+				// altitude hold climbing back to its target
+				searcher.Reset()
+				track.Set(false)
+				mux.ReleaseAll()
+				if searcher.Land() {
+					altitude.Disable()
+					dronex.Land()
+				}
+				break
+			}
+			// climbing searches move the height the altitude hold returns to
+			altitude.Yield(o.cfg.Tracking.Altitude.Yield)
+			autopilot.SetAxes(v, drone.AllAxes)
+		default:
+			if found {
+				searcher.Seen(ring.Detection(dronex).Position, time.Now())
+			}
+			searcher.Reset()
+			autopilot.Release()
+		}
+
+		if v, ok := altitude.Velocity(dronex.FlightData()); ok {
+			altitudeSource.SetAxes(mgl32.Vec4{0, v, 0, 0}, drone.AxisUp)
+		} else {
+			altitudeSource.Release()
+		}
+
+		drone.DrawCrosshair(dronex, frame)
+		drone.DrawControls(dronex, frame)
+		annotatedVideo.Publish(*frame)
+		return true
+	})
+}
+
+// flightHeight returns the telemetry height in m, 0 without telemetry
+func flightHeight(d drone.Drone) float32 {
+	fd := d.FlightData()
+	if fd == nil {
+		return 0
+	}
+	return float32(fd.Height) / 10
+}
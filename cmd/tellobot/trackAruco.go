@@ -0,0 +1,82 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"image"
+	"image/color"
+
+	"gocv.io/x/gocv"
+	"gocv.io/x/gocv/contrib"
+	"tellobot/drone"
+	"tellobot/tracking"
+)
+
+var (
+	markerSize float64
+	markerID   int
+)
+
+var trackArucoCommand = command{
+	name:  "track-aruco",
+	usage: "follow an aruco marker at the configured distance, T toggles tracking, N switches markers",
+	flags: func(fs *flag.FlagSet, o *options) {
+		fs.Float64Var(&markerSize, "marker-size", 0.08, "marker side length in m")
+		fs.IntVar(&markerID, "marker-id", -1, "marker to follow, -1 for any")
+	},
+	run: runTrackAruco,
+}
+
+func runTrackAruco(o *options, args []string) error {
+	window, err := o.display.Open("Aruco")
+	if err != nil {
+		return err
+	}
+	defer window.Close()
+
+	dict := contrib.NewArucoPredefinedDictionary(contrib.ArucoPredefinedDict_5x5_50)
+	defer dict.Close()
+
+	var track toggle
+	keys, err := o.keyBindings()
+	if err != nil {
+		return err
+	}
+	keys.SetAction("autopilot", func(d drone.Drone) {
+		fmt.Println("tracking:", track.Toggle())
+	})
+
+	d, err := o.newDrone(keys)
+	if err != nil {
+		return err
+	}
+	p := o.newPilot(d, keys)
+	defer p.Stop()
+
+	markers := tracking.NewArucoTarget(dict, markerID, float32(markerSize), d)
+	tracker := tracking.NewMultiTracker(o.cfg.Tracking.Tracker)
+	lockNext(keys, tracker)
+	target := tracker.Target(markers)
+	follower := tracking.NewFollower(o.cfg.Tracking.Follow)
+
+	return showVideo(d, window, func(frame *gocv.Mat) bool {
+		marker, ok := target.Detect(frame)
+		drawTracks(frame, tracker.Tracks(), tracker.Locked())
+		if corners, ids := markers.Markers(); len(corners) > 0 {
+			dict.DrawDetectedMarkers(frame, corners, ids, color.RGBA{255, 0, 0, 0})
+		}
+		if ok {
+			pos := marker.Position
+			distStr := fmt.Sprintf("d: %.0fcm, x: %.0fcm, y: %.0fcm", 100*pos.Z(), 100*pos.X(), 100*pos.Y())
+			textSize := gocv.GetTextSize(distStr, gocv.FontHersheySimplex, 0.5, 3)
+			gocv.PutText(frame, distStr, image.Pt(frame.Cols()/2-textSize.X/2, 40+textSize.Y/2), gocv.FontHersheySimplex, 0.8, color.RGBA{255, 255, 255, 0}, 4)
+		}
+
+		if track.On() {
+			follower.Drive(p.autopilot, marker, ok, frame.Cols(), frame.Rows())
+		} else {
+			p.autopilot.Release()
+		}
+		return true
+	})
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"image"
+	"image/color"
+	"net/http"
+
+	"gocv.io/x/gocv"
+	"tellobot/display"
+	"tellobot/drone"
+	"tellobot/groundcontrol"
+	"tellobot/metrics"
+	"tellobot/tracking"
+	"tellobot/tuning"
+)
+
+var (
+	colorMinArea float64
+	colorProfile string
+)
+
+var trackColorCommand = command{
+	name:  "track-color",
+	usage: "follow an object within the HSV thresholds, T toggles tracking, N switches objects, V tunes to a selected region",
+	flags: func(fs *flag.FlagSet, o *options) {
+		fs.Float64Var(&colorMinArea, "min-area", 100, "smallest object area in pixels")
+		fs.StringVar(&colorProfile, "color", "", "colour profile to start with")
+	},
+	run: runTrackColor,
+}
+
+func runTrackColor(o *options, args []string) error {
+	window, err := o.display.Open("Color")
+	if err != nil {
+		return err
+	}
+	defer window.Close()
+
+	objects := tracking.NewColorTarget(colorMinArea)
+	defer objects.Close()
+	profiles := tracking.NewColorProfiles(o.path("colors"))
+	if colorProfile != "" {
+		profile, err := profiles.Load(colorProfile)
+		if err != nil {
+			return err
+		}
+		if err := objects.SetProfile(profile); err != nil {
+			return err
+		}
+	}
+
+	// the thresholds are tuned live instead of with trackbars
+	tuning.Default.SetProfileDir(o.path("profiles"))
+	mux := http.NewServeMux()
+	mux.Handle("/tuning/", groundcontrol.Guard(o.cfg.Token, http.StripPrefix("/tuning", tuning.Default.Handler())))
+	mux.Handle("/colors/", groundcontrol.Guard(o.cfg.Token, http.StripPrefix("/colors", profiles.Handler(objects))))
+	mux.Handle("/metrics", metrics.Default.Handler())
+	go func() {
+		fmt.Println("tuning: listening on", o.cfg.Listen)
+		if err := http.ListenAndServe(o.cfg.Listen, mux); err != nil {
+			fmt.Println(err)
+		}
+	}()
+
+	var track, lock, tune toggle
+	keys, err := o.keyBindings()
+	if err != nil {
+		return err
+	}
+	keys.SetAction("autopilot", func(d drone.Drone) {
+		on := track.Toggle()
+		lock.Set(on)
+		fmt.Println("tracking:", on)
+	})
+	// auto tune samples the colours of a region selected with the mouse, or
+	// of the box in the middle of the frame without a window
+	W, H := o.cfg.Drone.FrameWidth, o.cfg.Drone.FrameHeight
+	sample := image.Rect(W/2-W/20, H/2-H/20, W/2+W/20, H/2+H/20)
+	selector, selectable := window.(display.Selector)
+	keys.SetAction("auto-tune", func(d drone.Drone) {
+		tune.Set(true)
+	})
+
+	d, err := o.newDrone(keys)
+	if err != nil {
+		return err
+	}
+	p := o.newPilot(d, keys)
+	defer p.Stop()
+
+	objects.Locate(d)
+	tracker := tracking.NewMultiTracker(o.cfg.Tracking.Tracker)
+	lockNext(keys, tracker)
+	target := tracker.Target(objects)
+	follower := tracking.NewFollower(o.cfg.Tracking.Follow)
+
+	return showVideo(d, window, func(frame *gocv.Mat) bool {
+		if tune.Take() {
+			region := sample
+			if selectable {
+				// the selection blocks the video, nobody steers meanwhile
+				p.autopilot.Release()
+				region = selector.SelectROI(*frame)
+			}
+			if !region.Empty() {
+				objects.AutoTune(region)
+			}
+		}
+		object, ok := target.Detect(frame)
+
+		W, H := frame.Cols(), frame.Rows()
+		if !selectable {
+			gocv.Rectangle(frame, sample, color.RGBA{0, 255, 0, 0}, 1)
+		}
+		drawTracks(frame, tracker.Tracks(), tracker.Locked())
+		if !track.On() {
+			p.autopilot.Release()
+			return true
+		}
+
+		// without a width in the profile the object keeps the size it had
+		// when T was pressed
+		if ok && lock.Take() {
+			follower.Lock(object)
+		}
+		follower.Drive(p.autopilot, object, ok, W, H)
+		return true
+	})
+}
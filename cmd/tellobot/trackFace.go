@@ -0,0 +1,76 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"image"
+	"image/color"
+
+	"gocv.io/x/gocv"
+	"tellobot/drone"
+	"tellobot/face"
+	"tellobot/tracking"
+)
+
+var trackFaceCommand = command{
+	name:  "track-face",
+	usage: "follow a face with a dnn detector at the configured distance, T toggles tracking, N switches faces",
+	flags: func(fs *flag.FlagSet, o *options) {
+		fs.Var(settingFlag{"face.model", o}, "model", "face detector model, caffe or onnx")
+		fs.Var(settingFlag{"face.netConfig", o}, "net-config", "face detector network configuration, empty for onnx")
+		fs.Var(settingFlag{"face.backend", o}, "backend", "dnn backend")
+		fs.Var(settingFlag{"face.target", o}, "target", "dnn target")
+	},
+	run: runTrackFace,
+}
+
+func runTrackFace(o *options, args []string) error {
+	detector, err := face.NewDetector(o.cfg.Face)
+	if err != nil {
+		return err
+	}
+	defer detector.Close()
+
+	window, err := o.display.Open("Face")
+	if err != nil {
+		return err
+	}
+	defer window.Close()
+
+	var track toggle
+	keys, err := o.keyBindings()
+	if err != nil {
+		return err
+	}
+	keys.SetAction("autopilot", func(d drone.Drone) {
+		fmt.Println("tracking:", track.Toggle())
+	})
+
+	d, err := o.newDrone(keys)
+	if err != nil {
+		return err
+	}
+	p := o.newPilot(d, keys)
+	defer p.Stop()
+
+	// the tracker keeps following the same person when others walk by
+	tracker := tracking.NewMultiTracker(o.cfg.Tracking.Tracker)
+	lockNext(keys, tracker)
+	target := tracker.Target(detector.Faces(d))
+	follower := tracking.NewFollower(o.cfg.Tracking.Follow)
+	return showVideo(d, window, func(frame *gocv.Mat) bool {
+		f, ok := target.Detect(frame)
+		drawTracks(frame, tracker.Tracks(), tracker.Locked())
+		if ok {
+			label := fmt.Sprintf("%.0f%% %.0fcm", 100*f.Confidence, 100*f.Position.Z())
+			gocv.PutText(frame, label, image.Pt(f.Box.Min.X, f.Box.Max.Y+15), gocv.FontHersheySimplex, 0.5, color.RGBA{0, 255, 0, 0}, 1)
+		}
+
+		if track.On() {
+			follower.Drive(p.autopilot, f, ok, frame.Cols(), frame.Rows())
+		} else {
+			p.autopilot.Release()
+		}
+		return true
+	})
+}
@@ -0,0 +1,142 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"image/color"
+	"time"
+
+	"gocv.io/x/gocv"
+	"gocv.io/x/gocv/contrib"
+	"tellobot/drone"
+)
+
+var (
+	recordFile string
+	videoFPS   float64
+)
+
+var viewCommand = command{
+	name:  "view",
+	usage: "show the video with the current controls",
+	run:   runView,
+}
+
+var recordCommand = command{
+	name:  "record",
+	usage: "fly manually and record the video",
+	flags: func(fs *flag.FlagSet, o *options) {
+		fs.StringVar(&recordFile, "o", "tellobot.avi", "video file")
+		fs.Float64Var(&videoFPS, "fps", 25, "frames per second")
+	},
+	run: runRecord,
+}
+
+var replayCommand = command{
+	name:  "replay",
+	usage: "play a recorded video file and show the detected markers",
+	flags: func(fs *flag.FlagSet, o *options) {
+		fs.Float64Var(&videoFPS, "fps", 25, "frames per second")
+	},
+	run: runReplay,
+}
+
+func runView(o *options, args []string) error {
+	window, err := o.display.Open("View")
+	if err != nil {
+		return err
+	}
+	defer window.Close()
+
+	keys, err := o.keyBindings()
+	if err != nil {
+		return err
+	}
+	d, err := o.newDrone(keys)
+	if err != nil {
+		return err
+	}
+
+	return showVideo(d, window, func(frame *gocv.Mat) bool {
+		drone.DrawCrosshair(d, frame)
+		drone.DrawControls(d, frame)
+		return true
+	})
+}
+
+func runRecord(o *options, args []string) error {
+	window, err := o.display.Open("Record")
+	if err != nil {
+		return err
+	}
+	defer window.Close()
+
+	keys, err := o.keyBindings()
+	if err != nil {
+		return err
+	}
+	d, err := o.newDrone(keys)
+	if err != nil {
+		return err
+	}
+
+	writer, err := gocv.VideoWriterFile(recordFile, "MJPG", videoFPS, o.cfg.Drone.FrameWidth, o.cfg.Drone.FrameHeight)
+	if err != nil {
+		return fmt.Errorf("video %s: %v", recordFile, err)
+	}
+	defer writer.Close()
+	fmt.Println("recording to", recordFile)
+
+	return showVideo(d, window, func(frame *gocv.Mat) bool {
+		if err := writer.Write(*frame); err != nil {
+			fmt.Println(err)
+			return false
+		}
+		return true
+	})
+}
+
+func runReplay(o *options, args []string) error {
+	if len(args) < 1 {
+		fmt.Println("How to run:\n\ttellobot replay [options] [video file]")
+		return nil
+	}
+
+	video, err := gocv.VideoCaptureFile(args[0])
+	if err != nil {
+		return fmt.Errorf("video %s: %v", args[0], err)
+	}
+	defer video.Close()
+
+	window, err := o.display.Open("Replay")
+	if err != nil {
+		return err
+	}
+	defer window.Close()
+
+	dict := contrib.NewArucoPredefinedDictionary(contrib.ArucoPredefinedDict_5x5_50)
+	defer dict.Close()
+
+	frame := gocv.NewMat()
+	defer frame.Close()
+
+	period := time.Duration(float64(time.Second) / videoFPS)
+	for {
+		start := time.Now()
+		if ok := video.Read(&frame); !ok || frame.Empty() {
+			return nil
+		}
+
+		corners, ids := dict.DetectMarkers(&frame)
+		if len(corners) > 0 {
+			dict.DrawDetectedMarkers(&frame, corners, ids, color.RGBA{255, 0, 0, 0})
+		}
+
+		window.IMShow(frame)
+		window.WaitKey(1)
+
+		if wait := period - time.Since(start); wait > 0 {
+			time.Sleep(wait)
+		}
+	}
+}
@@ -0,0 +1,216 @@
+package config
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v2"
+	"tellobot/drone"
+	"tellobot/face"
+	"tellobot/gesture"
+	"tellobot/logging"
+	"tellobot/race"
+	"tellobot/tracking"
+)
+
+// EnvPrefix starts the environment variables overriding the config, e.g.
+// TELLOBOT_DRONE_PORT for drone.port
+const EnvPrefix = "TELLOBOT_"
+
+// Config is the configuration of the whole bot. It is layered: the defaults
+// are overridden by the yaml file, then the environment, then flags.
+type Config struct {
+	Listen   string          `yaml:"listen"` // ground control address
+	Token    string          `yaml:"token"`  // required by ground control commands and tuning changes if set
+	Log      logging.Config  `yaml:"log"`
+	Drone    drone.Config    `yaml:"drone"`
+	Tracking tracking.Config `yaml:"tracking"`
+	Race     race.Config     `yaml:"race"`
+	Face     face.Config     `yaml:"face"`
+	Gesture  gesture.Config  `yaml:"gesture"`
+}
+
+func Default() Config {
+	return Config{
+		Listen:   "localhost:8080",
+		Log:      logging.DefaultConfig(),
+		Drone:    drone.DefaultConfig(),
+		Tracking: tracking.DefaultConfig(),
+		Race:     race.DefaultConfig(),
+		Face:     face.DefaultConfig(),
+		Gesture:  gesture.DefaultConfig(),
+	}
+}
+
+// Load returns the defaults overridden by the yaml file and the environment.
+// A missing file is not an error, unknown keys are.
+func Load(filename string) (Config, error) {
+	c := Default()
+
+	buf, err := ioutil.ReadFile(filename)
+	if err != nil && !os.IsNotExist(err) {
+		return c, err
+	}
+	if err == nil {
+		// strict decoding refuses map keys the defaults already have, so the
+		// keys are checked on an empty config and the values merged loosely
+		if err := yaml.UnmarshalStrict(buf, &Config{}); err != nil {
+			return c, fmt.Errorf("config %s: %v", filename, err)
+		}
+		if err := yaml.Unmarshal(buf, &c); err != nil {
+			return c, fmt.Errorf("config %s: %v", filename, err)
+		}
+	}
+
+	if err := c.ApplyEnv(os.Environ()); err != nil {
+		return c, err
+	}
+	return c, nil
+}
+
+func (c Config) Validate() error {
+	if err := c.Log.Validate(); err != nil {
+		return fmt.Errorf("log: %v", err)
+	}
+	if err := c.Drone.Validate(); err != nil {
+		return fmt.Errorf("drone: %v", err)
+	}
+	if err := c.Tracking.Validate(); err != nil {
+		return fmt.Errorf("tracking: %v", err)
+	}
+	if err := c.Race.Validate(); err != nil {
+		return fmt.Errorf("race: %v", err)
+	}
+	if err := c.Face.Validate(); err != nil {
+		return fmt.Errorf("face: %v", err)
+	}
+	if err := c.Gesture.Validate(); err != nil {
+		return fmt.Errorf("gesture: %v", err)
+	}
+	return nil
+}
+
+// Keys returns the names of all settable values, like drone.frameWidth
+func (c *Config) Keys() []string {
+	var keys []string
+	walk(reflect.ValueOf(c).Elem(), "", func(key string, v reflect.Value) {
+		keys = append(keys, key)
+	})
+	return keys
+}
+
+// Set changes a value by its key, e.g. Set("drone.type", "real"). Entries of
+// maps are set with the map key appended: drone.keys.bindings.q
+func (c *Config) Set(key string, value string) error {
+	found := false
+	var err error
+	walk(reflect.ValueOf(c).Elem(), "", func(k string, v reflect.Value) {
+		if v.Kind() == reflect.Map && strings.HasPrefix(key, k+".") {
+			if v.IsNil() {
+				v.Set(reflect.MakeMap(v.Type()))
+			}
+			v.SetMapIndex(reflect.ValueOf(strings.TrimPrefix(key, k+".")), reflect.ValueOf(value))
+			found = true
+			return
+		}
+		if k == key {
+			err = setValue(v, value)
+			found = true
+		}
+	})
+	if !found {
+		return fmt.Errorf("unknown config key %q", key)
+	}
+	if err != nil {
+		return fmt.Errorf("%s: %v", key, err)
+	}
+	return nil
+}
+
+// ApplyEnv sets every key with a matching variable in environ, given as
+// KEY=value like os.Environ
+func (c *Config) ApplyEnv(environ []string) error {
+	env := make(map[string]string)
+	for _, e := range environ {
+		if i := strings.Index(e, "="); i > 0 {
+			env[e[:i]] = e[i+1:]
+		}
+	}
+	for _, key := range c.Keys() {
+		if value, ok := env[EnvName(key)]; ok {
+			if err := c.Set(key, value); err != nil {
+				return fmt.Errorf("%s: %v", EnvName(key), err)
+			}
+		}
+	}
+	return nil
+}
+
+// EnvName returns the environment variable of a key
+func EnvName(key string) string {
+	return EnvPrefix + strings.ToUpper(strings.Replace(key, ".", "_", -1))
+}
+
+// walk calls fn for every value below v that is not a struct, named by the
+// yaml tags of its path
+func walk(v reflect.Value, prefix string, fn func(key string, v reflect.Value)) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		name := strings.Split(t.Field(i).Tag.Get("yaml"), ",")[0]
+		if name == "" || name == "-" {
+			continue
+		}
+		key := name
+		if prefix != "" {
+			key = prefix + "." + name
+		}
+		f := v.Field(i)
+		if f.Kind() == reflect.Struct {
+			walk(f, key, fn)
+		} else {
+			fn(key, f)
+		}
+	}
+}
+
+func setValue(v reflect.Value, s string) error {
+	if v.Type() == reflect.TypeOf(time.Duration(0)) {
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return err
+		}
+		v.SetInt(int64(d))
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(s)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return err
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i, err := strconv.ParseInt(s, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetInt(i)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(s, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetFloat(f)
+	default:
+		return fmt.Errorf("cannot set %v", v.Type())
+	}
+	return nil
+}
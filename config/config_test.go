@@ -0,0 +1,202 @@
+package config
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// writeConfig writes a yaml file to a temporary directory and returns its
+// name and a function removing it
+func writeConfig(t *testing.T, yaml string) (string, func()) {
+	dir, err := ioutil.TempDir("", "tellobot")
+	if err != nil {
+		t.Fatal(err)
+	}
+	filename := filepath.Join(dir, "tellobot.yaml")
+	if err := ioutil.WriteFile(filename, []byte(yaml), 0644); err != nil {
+		os.RemoveAll(dir)
+		t.Fatal(err)
+	}
+	return filename, func() { os.RemoveAll(dir) }
+}
+
+func TestLoadPrecedence(t *testing.T) {
+	filename, remove := writeConfig(t, `
+drone:
+  port: "1111"
+  frameWidth: 640
+  keys:
+    bindings:
+      q: counter-clockwise
+`)
+	defer remove()
+	os.Setenv("TELLOBOT_DRONE_PORT", "2222")
+	defer os.Unsetenv("TELLOBOT_DRONE_PORT")
+
+	c, err := Load(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the environment overrides the file, which overrides the defaults
+	if c.Drone.Port != "2222" {
+		t.Errorf("port %q, want the environment", c.Drone.Port)
+	}
+	if c.Drone.FrameWidth != 640 {
+		t.Errorf("frame width %d, want the file", c.Drone.FrameWidth)
+	}
+	if c.Drone.FrameHeight != Default().Drone.FrameHeight {
+		t.Errorf("frame height %d, want the default", c.Drone.FrameHeight)
+	}
+	if c.Drone.Keys.Bindings["q"] != "counter-clockwise" {
+		t.Errorf("bindings %v, want q from the file", c.Drone.Keys.Bindings)
+	}
+
+	// and flags override the environment
+	if err := c.Set("drone.port", "3333"); err != nil {
+		t.Fatal(err)
+	}
+	if c.Drone.Port != "3333" {
+		t.Errorf("port %q, want the flag", c.Drone.Port)
+	}
+}
+
+func TestLoadMissingFile(t *testing.T) {
+	c, err := Load(filepath.Join(os.TempDir(), "tellobot-missing.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(c, Default()) {
+		t.Errorf("got %+v, want the defaults", c)
+	}
+}
+
+func TestLoadErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		yaml string
+	}{
+		{"unknown key", "drone:\n  colour: red\n"},
+		{"unknown section", "camera:\n  tilt: 13\n"},
+		{"bad value", "drone:\n  frameWidth: wide\n"},
+		{"bad duration", "race:\n  search:\n    timeout: soon\n"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			filename, remove := writeConfig(t, test.yaml)
+			defer remove()
+			if _, err := Load(filename); err == nil {
+				t.Error("loaded")
+			}
+		})
+	}
+
+	os.Setenv("TELLOBOT_DRONE_FRAMEWIDTH", "wide")
+	defer os.Unsetenv("TELLOBOT_DRONE_FRAMEWIDTH")
+	_, err := Load(filepath.Join(os.TempDir(), "tellobot-missing.yaml"))
+	if err == nil || !strings.Contains(err.Error(), "TELLOBOT_DRONE_FRAMEWIDTH") {
+		t.Errorf("bad environment: %v", err)
+	}
+}
+
+// TestShippedConfig checks that the shipped file loads and holds the defaults
+func TestShippedConfig(t *testing.T) {
+	c, err := Load("../tellobot.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	for _, key := range c.Keys() {
+		want := Default()
+		if got, def := value(&c, key), value(&want, key); !reflect.DeepEqual(got, def) && !empty(got, def) {
+			t.Errorf("%s is %v, the default %v", key, got, def)
+		}
+	}
+}
+
+// value returns the value of a key
+func value(c *Config, key string) interface{} {
+	var v interface{}
+	walk(reflect.ValueOf(c).Elem(), "", func(k string, f reflect.Value) {
+		if k == key {
+			v = f.Interface()
+		}
+	})
+	return v
+}
+
+// empty reports whether both values are nil or empty maps or slices
+func empty(a interface{}, b interface{}) bool {
+	va, vb := reflect.ValueOf(a), reflect.ValueOf(b)
+	switch va.Kind() {
+	case reflect.Map, reflect.Slice:
+		return va.Len() == 0 && vb.Len() == 0
+	}
+	return false
+}
+
+func TestSet(t *testing.T) {
+	tests := []struct {
+		key   string
+		value string
+		ok    bool
+		check func(c Config) bool
+	}{
+		{"drone.type", "real", true, func(c Config) bool { return c.Drone.Type == "real" }},
+		{"drone.frameWidth", "640", true, func(c Config) bool { return c.Drone.FrameWidth == 640 }},
+		{"drone.frameWidth", "wide", false, nil},
+		{"drone.safety.maxHeight", "3.5", true, func(c Config) bool { return c.Drone.Safety.MaxHeight == 3.5 }},
+		{"drone.keys.bindings.q", "counter-clockwise", true, func(c Config) bool { return c.Drone.Keys.Bindings["q"] == "counter-clockwise" }},
+		{"drone.keys.bindings.h", "", true, func(c Config) bool { v, ok := c.Drone.Keys.Bindings["h"]; return ok && v == "" }},
+		{"drone.gamepad.buttons.l1", "hover", true, func(c Config) bool { return c.Drone.Gamepad.Buttons["l1"] == "hover" }},
+		{"drone.keys.holdTimeout", "250ms", true, func(c Config) bool { return c.Drone.Keys.HoldTimeout == 250*time.Millisecond }},
+		{"race.search.timeout", "1m30s", true, func(c Config) bool { return c.Race.Search.Timeout == 90*time.Second }},
+		{"race.search.timeout", "30", false, nil},
+		{"race.search.behaviours", "yaw-sweep", false, nil},
+		{"drone", "real", false, nil},
+		{"drone.colour", "red", false, nil},
+	}
+	for _, test := range tests {
+		c := Default()
+		err := c.Set(test.key, test.value)
+		if (err == nil) != test.ok {
+			t.Errorf("%s=%s: error %v", test.key, test.value, err)
+			continue
+		}
+		if test.check != nil && !test.check(c) {
+			t.Errorf("%s=%s not set", test.key, test.value)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	for _, s := range []string{
+		"drone.type=glider",
+		"drone.exposure=3",
+		"drone.keys.bindings.q=barrel-roll",
+		"drone.gamepad.deadZone=1",
+		"log.level=loud",
+		"race.search.timeout=-1s",
+	} {
+		c := Default()
+		i := strings.Index(s, "=")
+		if err := c.Set(s[:i], s[i+1:]); err != nil {
+			t.Fatalf("%s: %v", s, err)
+		}
+		if err := c.Validate(); err == nil {
+			t.Errorf("%s is valid", s)
+		}
+	}
+}
+
+func TestEnvName(t *testing.T) {
+	if name := EnvName("drone.keys.holdTimeout"); name != "TELLOBOT_DRONE_KEYS_HOLDTIMEOUT" {
+		t.Errorf("got %s", name)
+	}
+}
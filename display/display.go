@@ -0,0 +1,106 @@
+package display
+
+import (
+	"flag"
+	"fmt"
+	"image"
+	"os"
+	"path/filepath"
+
+	"gocv.io/x/gocv"
+)
+
+// Display shows frames, it replaces gocv.Window so the vision pipeline runs
+// without X as well
+type Display interface {
+	IMShow(img gocv.Mat)
+
+	// WaitKey returns the pressed key or -1, like gocv.Window.WaitKey
+	WaitKey(delay int) int
+
+	Close() error
+}
+
+// Selector is a display the user can select a region of a frame in
+type Selector interface {
+	// SelectROI shows the frame until a rectangle is drawn and confirmed,
+	// it returns an empty rectangle if the selection was cancelled
+	SelectROI(img gocv.Mat) image.Rectangle
+}
+
+// Window shows frames in an OpenCV window, regions are selected with the
+// mouse
+type Window struct {
+	*gocv.Window
+}
+
+func NewWindow(name string) *Window {
+	return &Window{gocv.NewWindow(name)}
+}
+
+// Headless drops all frames
+type Headless struct{}
+
+func NewHeadless() Headless {
+	return Headless{}
+}
+
+func (Headless) IMShow(img gocv.Mat) {}
+
+func (Headless) WaitKey(delay int) int {
+	return -1
+}
+
+func (Headless) Close() error {
+	return nil
+}
+
+// FileSink writes every frame as a numbered jpeg into a directory
+type FileSink struct {
+	Headless
+	dir   string
+	name  string
+	count int
+}
+
+func NewFileSink(dir string, name string) (*FileSink, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("frame directory %s: %v", dir, err)
+	}
+	return &FileSink{dir: dir, name: name}, nil
+}
+
+func (f *FileSink) IMShow(img gocv.Mat) {
+	if img.Empty() {
+		return
+	}
+	filename := filepath.Join(f.dir, fmt.Sprintf("%s-%06d.jpg", f.name, f.count))
+	if !gocv.IMWrite(filename, img) {
+		fmt.Println("display: could not write", filename)
+	}
+	f.count++
+}
+
+// Flags are the command line options selecting a display
+type Flags struct {
+	Headless bool
+	FrameDir string
+}
+
+// Register adds the -headless and -frames options
+func (f *Flags) Register(fs *flag.FlagSet) {
+	fs.BoolVar(&f.Headless, "headless", false, "run without a window")
+	fs.StringVar(&f.FrameDir, "frames", "", "write the shown frames as jpeg into this directory instead of a window")
+}
+
+// Open returns the display selected by the flags. Writing frames wins over
+// headless, if neither is set a window is opened.
+func (f Flags) Open(name string) (Display, error) {
+	if f.FrameDir != "" {
+		return NewFileSink(f.FrameDir, name)
+	}
+	if f.Headless {
+		return NewHeadless(), nil
+	}
+	return NewWindow(name), nil
+}
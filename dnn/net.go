@@ -0,0 +1,27 @@
+package dnn
+
+import (
+	"fmt"
+
+	"gocv.io/x/gocv"
+)
+
+// ReadNet loads a network, caffe, onnx, tensorflow and others are chosen by
+// the file extension of the model. The backend is one of default, halide,
+// openvino, opencv, vulkan or cuda, the target one of cpu, fp32, fp16, vpu,
+// vulkan, fpga, cuda or cudafp16.
+func ReadNet(model string, config string, backend string, target string) (gocv.Net, error) {
+	net := gocv.ReadNet(model, config)
+	if net.Empty() {
+		return net, fmt.Errorf("error reading network model from: %v %v", model, config)
+	}
+	if err := net.SetPreferableBackend(gocv.NetBackendType(gocv.ParseNetBackend(backend))); err != nil {
+		net.Close()
+		return net, fmt.Errorf("backend %s: %v", backend, err)
+	}
+	if err := net.SetPreferableTarget(gocv.NetTargetType(gocv.ParseNetTarget(target))); err != nil {
+		net.Close()
+		return net, fmt.Errorf("target %s: %v", target, err)
+	}
+	return net, nil
+}
@@ -0,0 +1,292 @@
+package drone
+
+import (
+	"sync"
+	"time"
+
+	"github.com/go-gl/mathgl/mgl32"
+)
+
+// AxisMask selects velocity axis in the order used by GetVelocity
+type AxisMask uint8
+
+const (
+	AxisRight AxisMask = 1 << iota
+	AxisUp
+	AxisForward
+	AxisClockwise
+
+	AxesTranslation = AxisRight | AxisUp | AxisForward
+	AllAxes         = AxesTranslation | AxisClockwise
+)
+
+// Source priorities, higher wins. Manual input always overrides everything.
+const (
+	PriorityManual    = 100
+	PriorityRemote    = 50
+	PriorityAltitude  = 20
+	PriorityAutopilot = 10
+)
+
+const muxPeriod = 50 * time.Millisecond
+
+// SourceConfig describes a source of velocity setpoints
+type SourceConfig struct {
+	Name     string
+	Priority int
+
+	// Timeout drops the source if it has not set a velocity for this long
+	Timeout time.Duration
+
+	// ReleaseDelay drops the source once it has commanded zero velocity for
+	// this long, e.g. when the pilot lets go of the sticks
+	ReleaseDelay time.Duration
+}
+
+// Source feeds velocity setpoints into a CommandMux
+type Source struct {
+	mux    *CommandMux
+	config SourceConfig
+
+	velocity    mgl32.Vec4
+	axes        AxisMask
+	updated     time.Time
+	lastNonZero time.Time
+}
+
+// CommandMux arbitrates velocity setpoints from several sources and is the
+// only one writing velocities to the drone. Every axis is driven by the
+// highest priority source currently claiming it; with no source the drone
+// hovers.
+type CommandMux struct {
+	drone Drone
+
+	mutex   sync.Mutex
+	sources []*Source
+	owners  [4]string
+	output  mgl32.Vec4
+	stop    chan struct{}
+}
+
+func NewCommandMux(d Drone) *CommandMux {
+	return &CommandMux{drone: d}
+}
+
+// AddSource registers a new source
+func (m *CommandMux) AddSource(config SourceConfig) *Source {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	s := &Source{mux: m, config: config}
+	m.sources = append(m.sources, s)
+	return s
+}
+
+// Start writes the arbitrated velocity to the drone periodically
+func (m *CommandMux) Start() {
+	m.mutex.Lock()
+	if m.stop != nil {
+		m.mutex.Unlock()
+		return
+	}
+	m.stop = make(chan struct{})
+	stop := m.stop
+	m.mutex.Unlock()
+
+	go func() {
+		ticker := time.NewTicker(muxPeriod)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+				m.Update()
+			}
+		}
+	}()
+}
+
+func (m *CommandMux) Stop() {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	if m.stop != nil {
+		close(m.stop)
+		m.stop = nil
+	}
+}
+
+// Update arbitrates once and writes the result to the drone
+func (m *CommandMux) Update() {
+	m.mutex.Lock()
+	now := time.Now()
+	var output mgl32.Vec4
+	for axis := 0; axis < 4; axis++ {
+		var owner *Source
+		for _, s := range m.sources {
+			if s.axes&(1<<uint(axis)) == 0 || !s.active(now) {
+				continue
+			}
+			if owner == nil || s.config.Priority > owner.config.Priority {
+				owner = s
+			}
+		}
+		if owner != nil {
+			output[axis] = owner.velocity[axis]
+			m.owners[axis] = owner.config.Name
+		} else {
+			m.owners[axis] = ""
+		}
+	}
+	m.output = output
+	m.mutex.Unlock()
+
+	SetVelocity(m.drone, output)
+}
+
+// ReleaseAll releases every source, so the drone hovers until the next
+// setpoint
+func (m *CommandMux) ReleaseAll() {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	for _, s := range m.sources {
+		s.axes = 0
+		s.velocity = mgl32.Vec4{}
+	}
+}
+
+// Output returns the last velocity written to the drone
+func (m *CommandMux) Output() mgl32.Vec4 {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	return m.output
+}
+
+// Owner returns the name of the source driving the given axis, or an empty
+// string if the axis is hovering
+func (m *CommandMux) Owner(axis AxisMask) string {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	for i := 0; i < 4; i++ {
+		if axis == 1<<uint(i) {
+			return m.owners[i]
+		}
+	}
+	return ""
+}
+
+// Set claims all axis with the given velocity
+func (s *Source) Set(v mgl32.Vec4) {
+	s.SetAxes(v, AllAxes)
+}
+
+// SetAxes claims only the selected axis, the other values of v are ignored
+func (s *Source) SetAxes(v mgl32.Vec4, axes AxisMask) {
+	s.mux.mutex.Lock()
+	defer s.mux.mutex.Unlock()
+
+	now := time.Now()
+	for i := 0; i < 4; i++ {
+		if axes&(1<<uint(i)) != 0 {
+			s.velocity[i] = v[i]
+		}
+	}
+	s.axes |= axes
+	s.updated = now
+	for i := 0; i < 4; i++ {
+		if s.axes&(1<<uint(i)) != 0 && s.velocity[i] != 0 {
+			s.lastNonZero = now
+		}
+	}
+}
+
+// Release gives up all axis until the next setpoint
+func (s *Source) Release() {
+	s.mux.mutex.Lock()
+	defer s.mux.mutex.Unlock()
+	s.axes = 0
+	s.velocity = mgl32.Vec4{}
+}
+
+// Active returns true if the source currently takes part in the arbitration
+func (s *Source) Active() bool {
+	s.mux.mutex.Lock()
+	defer s.mux.mutex.Unlock()
+	return s.active(time.Now())
+}
+
+func (s *Source) active(now time.Time) bool {
+	if s.axes == 0 {
+		return false
+	}
+	if s.config.Timeout > 0 && now.Sub(s.updated) > s.config.Timeout {
+		return false
+	}
+	if s.config.ReleaseDelay > 0 && now.Sub(s.lastNonZero) > s.config.ReleaseDelay {
+		return false
+	}
+	return true
+}
+
+// Drone returns a drone whose velocity commands are turned into setpoints of
+// this source, so existing controllers can be routed through the mux. All
+// other operations go straight to the underlying drone.
+func (s *Source) Drone() Drone {
+	return &sourceDrone{Drone: s.mux.drone, source: s}
+}
+
+type sourceDrone struct {
+	Drone
+	source *Source
+}
+
+func (d *sourceDrone) set(axis AxisMask, val float32) error {
+	var v mgl32.Vec4
+	for i := 0; i < 4; i++ {
+		if axis == 1<<uint(i) {
+			v[i] = val
+		}
+	}
+	d.source.SetAxes(v, axis)
+	return nil
+}
+
+func (d *sourceDrone) Right(val int) error {
+	return d.set(AxisRight, float32(val)/100.0)
+}
+
+func (d *sourceDrone) Left(val int) error {
+	return d.set(AxisRight, float32(val)/100.0*-1)
+}
+
+func (d *sourceDrone) Up(val int) error {
+	return d.set(AxisUp, float32(val)/100.0)
+}
+
+func (d *sourceDrone) Down(val int) error {
+	return d.set(AxisUp, float32(val)/100.0*-1)
+}
+
+func (d *sourceDrone) Forward(val int) error {
+	return d.set(AxisForward, float32(val)/100.0)
+}
+
+func (d *sourceDrone) Backward(val int) error {
+	return d.set(AxisForward, float32(val)/100.0*-1)
+}
+
+func (d *sourceDrone) Clockwise(val int) error {
+	return d.set(AxisClockwise, float32(val)/100.0)
+}
+
+func (d *sourceDrone) CounterClockwise(val int) error {
+	return d.set(AxisClockwise, float32(val)/100.0*-1)
+}
+
+func (d *sourceDrone) Hover() {
+	d.source.SetAxes(mgl32.Vec4{}, AxesTranslation)
+}
+
+func (d *sourceDrone) CeaseRotation() {
+	d.source.SetAxes(mgl32.Vec4{}, AxisClockwise)
+}
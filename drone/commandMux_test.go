@@ -0,0 +1,200 @@
+package drone
+
+import (
+	"testing"
+	"time"
+
+	"github.com/go-gl/mathgl/mgl32"
+)
+
+// velocityDrone records the velocities written by the mux in percent, every
+// other method panics
+type velocityDrone struct {
+	Drone
+	velocity [4]int
+}
+
+func (d *velocityDrone) Right(val int) error            { d.velocity[0] = val; return nil }
+func (d *velocityDrone) Left(val int) error             { d.velocity[0] = -val; return nil }
+func (d *velocityDrone) Up(val int) error               { d.velocity[1] = val; return nil }
+func (d *velocityDrone) Down(val int) error             { d.velocity[1] = -val; return nil }
+func (d *velocityDrone) Forward(val int) error          { d.velocity[2] = val; return nil }
+func (d *velocityDrone) Backward(val int) error         { d.velocity[2] = -val; return nil }
+func (d *velocityDrone) Clockwise(val int) error        { d.velocity[3] = val; return nil }
+func (d *velocityDrone) CounterClockwise(val int) error { d.velocity[3] = -val; return nil }
+
+// newTestMux returns a mux on a velocity drone with a low and a high
+// priority source
+func newTestMux(low SourceConfig, high SourceConfig) (*CommandMux, *velocityDrone, *Source, *Source) {
+	d := &velocityDrone{}
+	m := NewCommandMux(d)
+	low.Name, low.Priority = "low", PriorityAutopilot
+	high.Name, high.Priority = "high", PriorityManual
+	return m, d, m.AddSource(low), m.AddSource(high)
+}
+
+func TestCommandMuxPriority(t *testing.T) {
+	tests := []struct {
+		name   string
+		set    func(low, high *Source)
+		want   [4]int
+		owners [4]string
+	}{
+		{
+			"hovers without a source",
+			func(low, high *Source) {},
+			[4]int{0, 0, 0, 0},
+			[4]string{"", "", "", ""},
+		},
+		{
+			"low alone",
+			func(low, high *Source) { low.Set(mgl32.Vec4{0.1, 0.2, 0.3, 0.4}) },
+			[4]int{10, 20, 30, 40},
+			[4]string{"low", "low", "low", "low"},
+		},
+		{
+			"high wins",
+			func(low, high *Source) {
+				high.Set(mgl32.Vec4{-0.5, -0.5, -0.5, -0.5})
+				low.Set(mgl32.Vec4{0.1, 0.2, 0.3, 0.4})
+			},
+			[4]int{-50, -50, -50, -50},
+			[4]string{"high", "high", "high", "high"},
+		},
+		{
+			"per axis",
+			func(low, high *Source) {
+				low.Set(mgl32.Vec4{0.1, 0.2, 0.3, 0.4})
+				high.SetAxes(mgl32.Vec4{0.9, 0.5, 0.9, 0.9}, AxisUp)
+			},
+			[4]int{10, 50, 30, 40},
+			[4]string{"low", "high", "low", "low"},
+		},
+		{
+			"released high",
+			func(low, high *Source) {
+				low.Set(mgl32.Vec4{0.1, 0.2, 0.3, 0.4})
+				high.Set(mgl32.Vec4{0.5, 0.5, 0.5, 0.5})
+				high.Release()
+			},
+			[4]int{10, 20, 30, 40},
+			[4]string{"low", "low", "low", "low"},
+		},
+		{
+			"clamped to full power",
+			func(low, high *Source) { low.Set(mgl32.Vec4{2, -2, 0, 0}) },
+			[4]int{100, -100, 0, 0},
+			[4]string{"low", "low", "low", "low"},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			m, d, low, high := newTestMux(SourceConfig{}, SourceConfig{})
+			test.set(low, high)
+			m.Update()
+
+			if d.velocity != test.want {
+				t.Errorf("drone velocity %v, want %v", d.velocity, test.want)
+			}
+			for i, want := range test.owners {
+				if owner := m.Owner(1 << uint(i)); owner != want {
+					t.Errorf("axis %d owned by %q, want %q", i, owner, want)
+				}
+			}
+		})
+	}
+}
+
+func TestCommandMuxTimeout(t *testing.T) {
+	m, d, low, high := newTestMux(SourceConfig{}, SourceConfig{Timeout: 20 * time.Millisecond})
+	low.Set(mgl32.Vec4{0, 0, 0.1, 0})
+	high.Set(mgl32.Vec4{0, 0, 0.5, 0})
+	m.Update()
+	if d.velocity[2] != 50 {
+		t.Fatalf("forward %d before the timeout, want 50", d.velocity[2])
+	}
+
+	// a source that stopped sending gives the axis back
+	time.Sleep(40 * time.Millisecond)
+	m.Update()
+	if d.velocity[2] != 10 || high.Active() {
+		t.Errorf("forward %d after the timeout, want 10", d.velocity[2])
+	}
+
+	// and takes it again with the next setpoint
+	high.Set(mgl32.Vec4{0, 0, 0.5, 0})
+	m.Update()
+	if d.velocity[2] != 50 {
+		t.Errorf("forward %d after a new setpoint, want 50", d.velocity[2])
+	}
+}
+
+func TestCommandMuxReleaseDelay(t *testing.T) {
+	m, d, low, high := newTestMux(SourceConfig{}, SourceConfig{ReleaseDelay: 30 * time.Millisecond})
+	low.Set(mgl32.Vec4{0, 0, 0.1, 0})
+
+	// zero without a command before does not claim the axis
+	high.Set(mgl32.Vec4{})
+	m.Update()
+	if d.velocity[2] != 10 {
+		t.Fatalf("forward %d with only zero from high, want 10", d.velocity[2])
+	}
+
+	// letting go of the sticks holds the drone for the delay
+	high.Set(mgl32.Vec4{0, 0, 0.5, 0})
+	high.Set(mgl32.Vec4{})
+	m.Update()
+	if d.velocity[2] != 0 || m.Owner(AxisForward) != "high" {
+		t.Fatalf("forward %d by %q right after letting go, want 0 by high", d.velocity[2], m.Owner(AxisForward))
+	}
+
+	// zero setpoints do not extend it
+	time.Sleep(50 * time.Millisecond)
+	high.Set(mgl32.Vec4{})
+	m.Update()
+	if d.velocity[2] != 10 || m.Owner(AxisForward) != "low" {
+		t.Errorf("forward %d by %q after the delay, want 10 by low", d.velocity[2], m.Owner(AxisForward))
+	}
+}
+
+func TestCommandMuxReleaseAll(t *testing.T) {
+	m, d, low, high := newTestMux(SourceConfig{}, SourceConfig{})
+	low.Set(mgl32.Vec4{0.1, 0.2, 0.3, 0.4})
+	high.SetAxes(mgl32.Vec4{0, 0.5, 0, 0}, AxisUp)
+	m.Update()
+
+	m.ReleaseAll()
+	m.Update()
+	if d.velocity != [4]int{} || m.Output() != (mgl32.Vec4{}) {
+		t.Errorf("velocity %v after releasing all, want hover", d.velocity)
+	}
+	if low.Active() || high.Active() {
+		t.Error("source still active after releasing all")
+	}
+
+	// the sources can claim axis again
+	low.SetAxes(mgl32.Vec4{0, 0, 0.3, 0}, AxisForward)
+	m.Update()
+	if d.velocity != [4]int{0, 0, 30, 0} {
+		t.Errorf("velocity %v after a new setpoint, want %v", d.velocity, [4]int{0, 0, 30, 0})
+	}
+}
+
+func TestSourceDrone(t *testing.T) {
+	m, d, low, _ := newTestMux(SourceConfig{}, SourceConfig{})
+	sd := low.Drone()
+	sd.Forward(30)
+	sd.Left(20)
+	sd.CounterClockwise(10)
+	m.Update()
+	if d.velocity != [4]int{-20, 0, 30, -10} {
+		t.Fatalf("velocity %v, want %v", d.velocity, [4]int{-20, 0, 30, -10})
+	}
+
+	// hovering keeps the rotation
+	sd.Hover()
+	m.Update()
+	if d.velocity != [4]int{0, 0, 0, -10} {
+		t.Errorf("velocity %v after hovering, want %v", d.velocity, [4]int{0, 0, 0, -10})
+	}
+}
@@ -0,0 +1,84 @@
+package drone
+
+import (
+	"fmt"
+
+	"gobot.io/x/gobot/platforms/dji/tello"
+)
+
+// videoBitRates are the bit rate names used by the config and the key actions
+var videoBitRates = map[string]tello.VideoBitRate{
+	"auto": tello.VideoBitRateAuto,
+	"1m":   tello.VideoBitRate1M,
+	"1m5":  tello.VideoBitRate1M5,
+	"2m":   tello.VideoBitRate2M,
+	"3m":   tello.VideoBitRate3M,
+	"4m":   tello.VideoBitRate4M,
+}
+
+// Config describes the drone, its camera and manual control
+type Config struct {
+	Type        string        `yaml:"type"`        // fake or real
+	Port        string        `yaml:"port"`        // local udp port of the tello driver
+	Calibration string        `yaml:"calibration"` // empty picks one matching type and frame size
+	FrameWidth  int           `yaml:"frameWidth"`
+	FrameHeight int           `yaml:"frameHeight"`
+	BitRate     string        `yaml:"bitRate"`    // auto, 1m, 1m5, 2m, 3m or 4m
+	Exposure    int           `yaml:"exposure"`   // 0-2
+	CameraTilt  float32       `yaml:"cameraTilt"` // degrees the camera looks down
+	Keys        KeyConfig     `yaml:"keys"`
+	Safety      SafetyConfig  `yaml:"safety"`
+	Gamepad     GamepadConfig `yaml:"gamepad"`
+}
+
+func DefaultConfig() Config {
+	return Config{
+		Type:        "fake",
+		Port:        "8890",
+		FrameWidth:  defaultFrameX,
+		FrameHeight: defaultFrameY,
+		BitRate:     "1m",
+		Exposure:    1,
+		CameraTilt:  13,
+		Keys:        DefaultKeyConfig(),
+		Safety:      DefaultSafetyConfig(),
+		Gamepad:     DefaultGamepadConfig(),
+	}
+}
+
+func (c Config) Validate() error {
+	if _, err := ParseDroneType(c.Type); err != nil {
+		return err
+	}
+	if c.Port == "" {
+		return fmt.Errorf("no drone port")
+	}
+	if c.FrameWidth <= 0 || c.FrameHeight <= 0 {
+		return fmt.Errorf("invalid frame size %dx%d", c.FrameWidth, c.FrameHeight)
+	}
+	if _, ok := videoBitRates[c.BitRate]; !ok {
+		return fmt.Errorf("unknown bit rate %q", c.BitRate)
+	}
+	if c.Exposure < 0 || c.Exposure > 2 {
+		return fmt.Errorf("exposure %d not in [0, 2]", c.Exposure)
+	}
+	if err := c.Keys.Validate(); err != nil {
+		return err
+	}
+	if err := c.Safety.Validate(); err != nil {
+		return err
+	}
+	return c.Gamepad.Validate()
+}
+
+// CalibrationFile returns the configured calibration or the one shipped for
+// the drone type and frame width
+func (c Config) CalibrationFile() string {
+	if c.Calibration != "" {
+		return c.Calibration
+	}
+	if c.Type == "real" {
+		return fmt.Sprintf("drone-camera-calibration-%d.yaml", c.FrameWidth)
+	}
+	return "camera-calibration.yaml"
+}
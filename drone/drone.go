@@ -1,6 +1,7 @@
 package drone
 
 import (
+	"fmt"
 	"gobot.io/x/gobot"
 	"gobot.io/x/gobot/platforms/keyboard"
 	"image"
@@ -10,8 +11,11 @@ import (
 	"gobot.io/x/gobot/platforms/dji/tello"
 	"gocv.io/x/gocv"
 	"gocv.io/x/gocv/contrib"
+	"tellobot/logging"
 )
 
+var log = logging.Default.With("drone")
+
 type DroneType int
 
 const (
@@ -42,6 +46,9 @@ type Drone interface {
 	// PalmLand tells drone to come in for a hand landing.
 	PalmLand() (err error)
 
+	// Emergency stops the motors immediately, the drone will fall.
+	Emergency() (err error)
+
 	// SetExposure sets the drone camera exposure level. Valid levels are 0, 1, and 2.
 	SetExposure(level int) (err error)
 
@@ -109,6 +116,10 @@ type Drone interface {
 	// ParseFlightData from drone
 	ParseFlightData(b []byte) (fd *tello.FlightData, err error)
 
+	// FlightData returns the latest flight data received from the drone or nil
+	// if none has been received yet
+	FlightData() *tello.FlightData
+
 	// GetVelocity gives the currently active speed in four axis
 	// x-axis is velocity in right direction with values from -1.0 to 1.0
 	// y-axis is velocity in up direction with values from -1.0 to 1.0
@@ -136,21 +147,42 @@ type Drone interface {
 
 type handleKey func(event keyboard.KeyEvent, drone Drone)
 
+// ParseDroneType turns fake or real into a DroneType
+func ParseDroneType(name string) (DroneType, error) {
+	switch name {
+	case "fake":
+		return DroneFake, nil
+	case "real":
+		return DroneReal, nil
+	}
+	return DroneFake, fmt.Errorf("unknown drone %q, use fake or real", name)
+}
+
 func New(droneType DroneType, fn handleKey, cameraCalibrationFilename string) Drone {
+	config := DefaultConfig()
+	if droneType == DroneReal {
+		config.Type = "real"
+	}
+	config.Calibration = cameraCalibrationFilename
+	return NewFromConfig(config, fn)
+}
+
+// NewFromConfig creates the configured drone, the config has to be valid
+func NewFromConfig(config Config, fn handleKey) Drone {
 
 	keys := keyboard.NewDriver()
 	var d Drone
 
+	droneType, _ := ParseDroneType(config.Type)
 	switch droneType {
 	case DroneFake:
-		dt := &fakeDriver{}
-		dt.cameraCalibrationFilename = cameraCalibrationFilename
-		d = dt
+		d = NewFake(config)
 	case DroneReal:
 		dt := &realDriver{
-			Driver: *tello.NewDriver("8890"),
+			Driver: *tello.NewDriver(config.Port),
+			config: config,
 		}
-		dt.cameraCalibrationFilename = cameraCalibrationFilename
+		dt.cameraCalibrationFilename = config.CalibrationFile()
 		d = dt
 	}
 
@@ -170,6 +202,41 @@ func New(droneType DroneType, fn handleKey, cameraCalibrationFilename string) Dr
 	return d
 }
 
+// NewFake returns the simulated drone without keyboard input, e.g. for tests.
+// Only Init needs the webcam.
+func NewFake(config Config) Drone {
+	return &fakeDriver{config: config, cameraCalibrationFilename: config.CalibrationFile()}
+}
+
+// SetVelocity commands all four axis at once. The velocity uses the same
+// convention as GetVelocity, values are clamped to -1.0 to 1.0.
+func SetVelocity(d Drone, v mgl32.Vec4) {
+	power := func(val float32) int {
+		return int(mgl32.Clamp(val, -1, 1) * 100)
+	}
+
+	if x := power(v[0]); x >= 0 {
+		d.Right(x)
+	} else {
+		d.Left(-x)
+	}
+	if y := power(v[1]); y >= 0 {
+		d.Up(y)
+	} else {
+		d.Down(-y)
+	}
+	if z := power(v[2]); z >= 0 {
+		d.Forward(z)
+	} else {
+		d.Backward(-z)
+	}
+	if w := power(v[3]); w >= 0 {
+		d.Clockwise(w)
+	} else {
+		d.CounterClockwise(-w)
+	}
+}
+
 func DrawCrosshair(d Drone, img *gocv.Mat) {
 
 	s := float32(img.Rows())
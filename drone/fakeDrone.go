@@ -1,7 +1,9 @@
 package drone
 
 import (
-	"fmt"
+	"image"
+	"sync"
+	"time"
 
 	"github.com/go-gl/mathgl/mgl32"
 	"gobot.io/x/gobot/platforms/dji/tello"
@@ -10,16 +12,31 @@ import (
 )
 
 type fakeDriver struct {
-	velocity                  mgl32.Vec4
 	webcam                    *gocv.VideoCapture
 	cameraCalibrationFilename string
 	camMatrix                 gocv.Mat
 	distCoeffs                gocv.Mat
 	cameraToDrone             mgl32.Mat3
-}
+	config                    Config
+
+	// the simulated state is changed by the mux, the safety monitor and the
+	// ground control server at the same time
+	stateMutex     sync.Mutex
+	velocity       mgl32.Vec4
+	flying         bool
+	height         float32
+	flightDataTime time.Time
+}
+
+const (
+	// fakeMaxSpeed is the simulated speed in m/s at full power
+	fakeMaxSpeed = 1.0
+	// fakeTakeOffHeight is the height in m the drone climbs to on take off
+	fakeTakeOffHeight = 0.8
+)
 
 func (d *fakeDriver) Init() error {
-	d.cameraToDrone = mgl32.Rotate3DX(mgl32.DegToRad(-13.0))
+	d.cameraToDrone = mgl32.Rotate3DX(mgl32.DegToRad(-d.config.CameraTilt))
 	var err error
 	d.webcam, err = gocv.VideoCaptureDevice(0)
 	if err != nil {
@@ -35,7 +52,11 @@ func (d *fakeDriver) Halt() (err error) {
 }
 
 func (d *fakeDriver) TakeOff() (err error) {
-	fmt.Println("drone: TakeOff")
+	log.Info("take off", nil)
+	d.stateMutex.Lock()
+	defer d.stateMutex.Unlock()
+	d.flying = true
+	d.height = fakeTakeOffHeight
 	return nil
 }
 
@@ -44,7 +65,21 @@ func (d *fakeDriver) ThrowTakeOff() (err error) {
 }
 
 func (d *fakeDriver) Land() (err error) {
-	fmt.Println("drone: Land")
+	log.Info("land", nil)
+	d.stateMutex.Lock()
+	defer d.stateMutex.Unlock()
+	d.flying = false
+	d.height = 0
+	return nil
+}
+
+func (d *fakeDriver) Emergency() (err error) {
+	log.Warn("emergency", nil)
+	d.stateMutex.Lock()
+	defer d.stateMutex.Unlock()
+	d.flying = false
+	d.height = 0
+	d.velocity = mgl32.Vec4{}
 	return nil
 }
 
@@ -77,52 +112,68 @@ func (d *fakeDriver) Rate() (err error) {
 }
 
 func (d *fakeDriver) Right(val int) error {
-	d.velocity[0] = float32(val) / 100.0
+	d.setAxis(0, float32(val)/100.0)
+	commands.IncLabel("right")
 	return nil
 }
 
 func (d *fakeDriver) Left(val int) error {
-	d.velocity[0] = float32(val) / 100.0 * -1
+	d.setAxis(0, -float32(val)/100.0)
+	commands.IncLabel("right")
 	return nil
 }
 
 func (d *fakeDriver) Up(val int) error {
-	d.velocity[1] = float32(val) / 100.0
+	d.setAxis(1, float32(val)/100.0)
+	commands.IncLabel("up")
 	return nil
 }
 
 func (d *fakeDriver) Down(val int) error {
-	d.velocity[1] = float32(val) / 100.0 * -1
+	d.setAxis(1, -float32(val)/100.0)
+	commands.IncLabel("up")
 	return nil
 }
 
 func (d *fakeDriver) Forward(val int) error {
-	d.velocity[2] = float32(val) / 100.0
+	d.setAxis(2, float32(val)/100.0)
+	commands.IncLabel("forward")
 	return nil
 }
 
 func (d *fakeDriver) Backward(val int) error {
-	d.velocity[2] = float32(val) / 100.0 * -1
+	d.setAxis(2, -float32(val)/100.0)
+	commands.IncLabel("forward")
 	return nil
 }
 
 func (d *fakeDriver) Clockwise(val int) error {
-	d.velocity[3] = float32(val) / 100.0
+	d.setAxis(3, float32(val)/100.0)
+	commands.IncLabel("clockwise")
 	return nil
 }
 
 func (d *fakeDriver) CounterClockwise(val int) error {
-	d.velocity[3] = float32(val) / 100.0 * -1
+	d.setAxis(3, -float32(val)/100.0)
+	commands.IncLabel("clockwise")
 	return nil
 }
 
+func (d *fakeDriver) setAxis(axis int, v float32) {
+	d.stateMutex.Lock()
+	defer d.stateMutex.Unlock()
+	d.velocity[axis] = v
+}
+
 func (d *fakeDriver) Hover() {
+	d.stateMutex.Lock()
+	defer d.stateMutex.Unlock()
 	d.velocity[0] = 0.0
 	d.velocity[1] = 0.0
 	d.velocity[2] = 0.0
 }
 func (d *fakeDriver) CeaseRotation() {
-	d.velocity[3] = 0.0
+	d.setAxis(3, 0.0)
 }
 
 func (d *fakeDriver) Bounce() (err error) {
@@ -153,11 +204,55 @@ func (d *fakeDriver) ParseFlightData(b []byte) (fd *tello.FlightData, err error)
 	return nil, nil
 }
 
+// FlightData simulates the telemetry from the commanded velocity
+func (d *fakeDriver) FlightData() *tello.FlightData {
+	d.stateMutex.Lock()
+	defer d.stateMutex.Unlock()
+	now := time.Now()
+	if d.flying && !d.flightDataTime.IsZero() {
+		dt := float32(now.Sub(d.flightDataTime).Seconds())
+		d.height += d.velocity[1] * fakeMaxSpeed * dt
+		if d.height < 0 {
+			d.height = 0
+		}
+	}
+	d.flightDataTime = now
+
+	fd := &tello.FlightData{
+		BatteryPercentage: 100,
+		WifiStrength:      100,
+		EmSky:             d.flying,
+		EmGround:          !d.flying,
+		Height:            int16(d.height * 10),
+	}
+	if d.flying {
+		// speeds are reported in dm/s
+		fd.NorthSpeed = int16(d.velocity[2] * fakeMaxSpeed * 10)
+		fd.EastSpeed = int16(d.velocity[0] * fakeMaxSpeed * 10)
+		fd.VerticalSpeed = int16(d.velocity[1] * fakeMaxSpeed * 10)
+	}
+	recordFlightData(fd)
+	return fd
+}
+
 func (d *fakeDriver) GetVelocity() mgl32.Vec4 {
+	d.stateMutex.Lock()
+	defer d.stateMutex.Unlock()
 	return d.velocity
 }
 func (d *fakeDriver) ReadVideoFrame(frame *gocv.Mat) error {
-	d.webcam.Read(frame)
+	start := time.Now()
+	if ok := d.webcam.Read(frame); !ok || frame.Empty() {
+		framesDropped.Inc()
+		return nil
+	}
+	framesDecoded.Inc()
+	frameWait.ObserveSince(start)
+	// webcams do not know the drone frame sizes, scale to match the calibration
+	w, h := d.config.FrameWidth, d.config.FrameHeight
+	if frame.Cols() != w || frame.Rows() != h {
+		gocv.Resize(*frame, frame, image.Pt(w, h), 0, 0, gocv.InterpolationLinear)
+	}
 	return nil
 }
 
@@ -0,0 +1,299 @@
+package drone
+
+import (
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/go-gl/mathgl/mgl32"
+	"gobot.io/x/gobot"
+	"gobot.io/x/gobot/platforms/joystick"
+)
+
+const gamepadPeriod = 50 * time.Millisecond
+
+// Stick axis of a gamepad, the layout is mode 2: the left stick yaws and
+// climbs, the right stick moves forward and sideways
+const (
+	StickLeftX = iota
+	StickLeftY
+	StickRightX
+	StickRightY
+)
+
+// GamepadMapping translates the events of one controller type into stick
+// axis and button names. Buttons use position names so bindings work the same
+// on every controller: north, south, east, west, start, select, l1, r1.
+type GamepadMapping struct {
+	Config  string         // gobot joystick config
+	Axes    map[string]int // event -> stick axis
+	Buttons map[string]string
+}
+
+var GamepadMappings = map[string]*GamepadMapping{
+	"dualshock4": {
+		Config: joystick.Dualshock4,
+		Axes:   defaultStickEvents,
+		Buttons: map[string]string{
+			"triangle_press": "north",
+			"x_press":        "south",
+			"circle_press":   "east",
+			"square_press":   "west",
+			"options_press":  "start",
+			"share_press":    "select",
+			"l1_press":       "l1",
+			"r1_press":       "r1",
+		},
+	},
+	"dualshock3": {
+		Config: joystick.Dualshock3,
+		Axes:   defaultStickEvents,
+		Buttons: map[string]string{
+			"triangle_press": "north",
+			"x_press":        "south",
+			"circle_press":   "east",
+			"square_press":   "west",
+			"start_press":    "start",
+			"select_press":   "select",
+			"l1_press":       "l1",
+			"r1_press":       "r1",
+		},
+	},
+	"xbox360": {
+		Config: joystick.Xbox360,
+		Axes:   defaultStickEvents,
+		Buttons: map[string]string{
+			"y_press":     "north",
+			"a_press":     "south",
+			"b_press":     "east",
+			"x_press":     "west",
+			"start_press": "start",
+			"back_press":  "select",
+			"lb_press":    "l1",
+			"rb_press":    "r1",
+		},
+	},
+}
+
+var defaultStickEvents = map[string]int{
+	joystick.LeftX:  StickLeftX,
+	joystick.LeftY:  StickLeftY,
+	joystick.RightX: StickRightX,
+	joystick.RightY: StickRightY,
+}
+
+// GamepadConfig holds the tuning of a gamepad
+type GamepadConfig struct {
+	Mapping  string            `yaml:"mapping"`  // key of GamepadMappings
+	DeadZone float32           `yaml:"deadZone"` // fraction of full deflection ignored around center
+	Expo     float32           `yaml:"expo"`     // 0 is linear, 1 fully cubic
+	MaxPower float32           `yaml:"maxPower"` // velocity at full deflection
+	Invert   []string          `yaml:"invert"`   // axes flipped: right, up, forward or clockwise
+	Buttons  map[string]string `yaml:"buttons"`  // button -> action
+}
+
+// velocityAxes names the velocity axes in the GetVelocity order
+var velocityAxes = []string{"right", "up", "forward", "clockwise"}
+
+func DefaultGamepadConfig() GamepadConfig {
+	return GamepadConfig{
+		Mapping:  "dualshock4",
+		DeadZone: 0.1,
+		Expo:     0.3,
+		MaxPower: 1.0,
+		Buttons: map[string]string{
+			"north":  "takeoff",
+			"south":  "land",
+			"east":   "autopilot",
+			"west":   "hover",
+			"select": "emergency",
+		},
+	}
+}
+
+// Validate checks the ranges and that the mapping, the inverted axes, the
+// buttons and their actions are known
+func (c GamepadConfig) Validate() error {
+	if _, ok := GamepadMappings[c.Mapping]; !ok {
+		return fmt.Errorf("unknown gamepad mapping %q", c.Mapping)
+	}
+	if c.DeadZone < 0 || c.DeadZone >= 1 {
+		return fmt.Errorf("dead zone must be between 0 and 1")
+	}
+	if c.Expo < 0 || c.Expo > 1 {
+		return fmt.Errorf("expo %v not in [0, 1]", c.Expo)
+	}
+	if c.MaxPower <= 0 || c.MaxPower > 1 {
+		return fmt.Errorf("max power %v not in (0, 1]", c.MaxPower)
+	}
+	for _, name := range c.Invert {
+		if velocityAxis(name) < 0 {
+			return fmt.Errorf("unknown gamepad axis %q to invert", name)
+		}
+	}
+	actions := DefaultKeyBindings().actions
+	for button, action := range c.Buttons {
+		if !gamepadButtons[button] {
+			return fmt.Errorf("unknown gamepad button %q", button)
+		}
+		if _, ok := actions[action]; action != "" && !ok {
+			return fmt.Errorf("unknown action %q", action)
+		}
+	}
+	return nil
+}
+
+// gamepadButtons are the position names of the buttons every mapping has
+var gamepadButtons = map[string]bool{
+	"north": true, "south": true, "east": true, "west": true,
+	"start": true, "select": true, "l1": true, "r1": true,
+}
+
+// velocityAxis returns the GetVelocity index of an axis name or -1
+func velocityAxis(name string) int {
+	for i, axis := range velocityAxes {
+		if name == axis {
+			return i
+		}
+	}
+	return -1
+}
+
+// Gamepad turns controller events into velocities and actions. Actions are
+// looked up in the key bindings so both inputs share them.
+type Gamepad struct {
+	mutex sync.Mutex
+
+	config  GamepadConfig
+	mapping *GamepadMapping
+	keys    *KeyBindings
+	source  *Source
+	sticks  [4]float32 // raw -1.0 to 1.0
+	signs   mgl32.Vec4 // -1 for inverted velocity axes
+}
+
+func NewGamepad(config GamepadConfig, keys *KeyBindings) (*Gamepad, error) {
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+	signs := mgl32.Vec4{1, 1, 1, 1}
+	for _, name := range config.Invert {
+		signs[velocityAxis(name)] = -1
+	}
+	return &Gamepad{
+		config:  config,
+		mapping: GamepadMappings[config.Mapping],
+		keys:    keys,
+		signs:   signs,
+	}, nil
+}
+
+// SetSource routes the stick velocities through a command mux source instead
+// of driving the drone directly
+func (g *Gamepad) SetSource(s *Source) {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+	g.source = s
+}
+
+// Connect opens the first joystick and starts sending velocities to d
+func (g *Gamepad) Connect(d Drone) error {
+	adaptor := joystick.NewAdaptor()
+	stick := joystick.NewDriver(adaptor, g.mapping.Config)
+
+	for event := range g.mapping.Axes {
+		event := event
+		stick.On(event, func(data interface{}) {
+			g.HandleAxis(event, data.(int16))
+		})
+	}
+	for event := range g.mapping.Buttons {
+		event := event
+		stick.On(event, func(data interface{}) {
+			g.HandleButton(event, d)
+		})
+	}
+
+	robot := gobot.NewRobot("gamepad",
+		[]gobot.Connection{adaptor},
+		[]gobot.Device{stick},
+	)
+	if err := robot.Start(false); err != nil {
+		return err
+	}
+
+	gobot.Every(gamepadPeriod, func() {
+		g.Update(d)
+	})
+	return nil
+}
+
+// HandleAxis stores a raw axis event
+func (g *Gamepad) HandleAxis(event string, value int16) {
+	axis, ok := g.mapping.Axes[event]
+	if !ok {
+		return
+	}
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+	g.sticks[axis] = mgl32.Clamp(float32(value)/math.MaxInt16, -1, 1)
+}
+
+// HandleButton runs the action bound to a button event
+func (g *Gamepad) HandleButton(event string, d Drone) {
+	button, ok := g.mapping.Buttons[event]
+	if !ok {
+		return
+	}
+	action, ok := g.config.Buttons[button]
+	if !ok || g.keys == nil {
+		return
+	}
+	g.keys.Run(action, d)
+}
+
+// Velocity returns the stick deflection as velocity in the GetVelocity
+// convention after dead zone, expo and inversion
+func (g *Gamepad) Velocity() mgl32.Vec4 {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+
+	// stick y axis are negative when pushed forward
+	return mgl32.Vec4{
+		g.signs[0] * g.shape(g.sticks[StickRightX]),
+		g.signs[1] * g.shape(-g.sticks[StickLeftY]),
+		g.signs[2] * g.shape(-g.sticks[StickRightY]),
+		g.signs[3] * g.shape(g.sticks[StickLeftX]),
+	}
+}
+
+// Update sends the current stick velocity to the source or the drone
+func (g *Gamepad) Update(d Drone) {
+	v := g.Velocity()
+
+	g.mutex.Lock()
+	source := g.source
+	g.mutex.Unlock()
+
+	if source != nil {
+		source.Set(v)
+	} else {
+		SetVelocity(d, v)
+	}
+}
+
+// shape applies dead zone, expo and power scaling to a -1.0 to 1.0 value
+func (g *Gamepad) shape(x float32) float32 {
+	sign := float32(1)
+	if x < 0 {
+		sign = -1
+		x = -x
+	}
+	if x <= g.config.DeadZone {
+		return 0
+	}
+	x = (x - g.config.DeadZone) / (1 - g.config.DeadZone)
+	x = (1-g.config.Expo)*x + g.config.Expo*x*x*x
+	return sign * x * g.config.MaxPower
+}
@@ -0,0 +1,160 @@
+package drone
+
+import (
+	"math"
+	"testing"
+
+	"github.com/go-gl/mathgl/mgl32"
+	"gobot.io/x/gobot/platforms/joystick"
+)
+
+// linearGamepad has no dead zone, no expo and full power unless changed
+func linearGamepad(t *testing.T, change func(c *GamepadConfig)) *Gamepad {
+	c := DefaultGamepadConfig()
+	c.DeadZone = 0
+	c.Expo = 0
+	c.MaxPower = 1
+	if change != nil {
+		change(&c)
+	}
+	g, err := NewGamepad(c, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return g
+}
+
+func approx(a mgl32.Vec4, b mgl32.Vec4) bool {
+	for i := range a {
+		if math.Abs(float64(a[i]-b[i])) > 1e-3 {
+			return false
+		}
+	}
+	return true
+}
+
+func TestGamepadHandleAxis(t *testing.T) {
+	g := linearGamepad(t, nil)
+
+	// mode 2: pushing the right stick forward is a negative y
+	g.HandleAxis(joystick.RightY, -math.MaxInt16)
+	g.HandleAxis(joystick.RightX, math.MaxInt16/2)
+	g.HandleAxis(joystick.LeftY, math.MinInt16)
+	g.HandleAxis(joystick.LeftX, -math.MaxInt16/4)
+	g.HandleAxis("l2", math.MaxInt16)
+
+	want := mgl32.Vec4{0.5, 1, 1, -0.25}
+	if v := g.Velocity(); !approx(v, want) {
+		t.Errorf("got %v, want %v", v, want)
+	}
+}
+
+func TestGamepadShape(t *testing.T) {
+	tests := []struct {
+		name   string
+		config func(c *GamepadConfig)
+		stick  float32 // right stick x deflection
+		want   float32
+	}{
+		{"linear", nil, 0.6, 0.6},
+		{"inside the dead zone", func(c *GamepadConfig) { c.DeadZone = 0.2 }, 0.15, 0},
+		{"edge of the dead zone", func(c *GamepadConfig) { c.DeadZone = 0.2 }, -0.2, 0},
+		{"dead zone rescales", func(c *GamepadConfig) { c.DeadZone = 0.2 }, 0.6, 0.5},
+		{"dead zone keeps full deflection", func(c *GamepadConfig) { c.DeadZone = 0.2 }, -1, -1},
+		{"full expo is cubic", func(c *GamepadConfig) { c.Expo = 1 }, 0.5, 0.125},
+		{"expo blends", func(c *GamepadConfig) { c.Expo = 0.5 }, -0.5, -0.3125},
+		{"expo keeps full deflection", func(c *GamepadConfig) { c.Expo = 0.3 }, 1, 1},
+		{"max power", func(c *GamepadConfig) { c.MaxPower = 0.4 }, 1, 0.4},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			g := linearGamepad(t, test.config)
+			g.HandleAxis(joystick.RightX, int16(test.stick*math.MaxInt16))
+			if v := g.Velocity(); math.Abs(float64(v[0]-test.want)) > 1e-3 {
+				t.Errorf("stick %v: got %v, want %v", test.stick, v[0], test.want)
+			}
+		})
+	}
+}
+
+func TestGamepadInvert(t *testing.T) {
+	g := linearGamepad(t, func(c *GamepadConfig) {
+		c.Invert = []string{"forward", "clockwise"}
+	})
+	g.HandleAxis(joystick.RightY, -math.MaxInt16)
+	g.HandleAxis(joystick.RightX, math.MaxInt16)
+	g.HandleAxis(joystick.LeftY, -math.MaxInt16)
+	g.HandleAxis(joystick.LeftX, math.MaxInt16)
+
+	want := mgl32.Vec4{1, 1, -1, -1}
+	if v := g.Velocity(); !approx(v, want) {
+		t.Errorf("got %v, want %v", v, want)
+	}
+
+	c := DefaultGamepadConfig()
+	c.Invert = []string{"pitch"}
+	if _, err := NewGamepad(c, nil); err == nil {
+		t.Error("unknown axis inverted")
+	}
+}
+
+func TestGamepadConfig(t *testing.T) {
+	for _, change := range []func(c *GamepadConfig){
+		func(c *GamepadConfig) { c.Mapping = "n64" },
+		func(c *GamepadConfig) { c.DeadZone = 1 },
+		func(c *GamepadConfig) { c.DeadZone = -0.1 },
+		func(c *GamepadConfig) { c.Expo = 1.5 },
+		func(c *GamepadConfig) { c.MaxPower = 0 },
+		func(c *GamepadConfig) { c.Buttons = map[string]string{"cross": "land"} },
+	} {
+		c := DefaultGamepadConfig()
+		change(&c)
+		if _, err := NewGamepad(c, nil); err == nil {
+			t.Errorf("config %+v is valid", c)
+		}
+	}
+}
+
+func TestGamepadUpdate(t *testing.T) {
+	g := linearGamepad(t, nil)
+	d := &fakeDriver{config: DefaultConfig()}
+	g.HandleAxis(joystick.RightY, -math.MaxInt16)
+	g.Update(d)
+	if v := d.GetVelocity(); !approx(v, mgl32.Vec4{0, 0, 1, 0}) {
+		t.Errorf("drone velocity %v", v)
+	}
+
+	// with a source the mux decides
+	mux := NewCommandMux(d)
+	source := mux.AddSource(SourceConfig{Name: "gamepad", Priority: PriorityManual})
+	g.SetSource(source)
+	g.HandleAxis(joystick.RightY, 0)
+	g.HandleAxis(joystick.LeftX, math.MaxInt16)
+	g.Update(d)
+	if v := d.GetVelocity(); !approx(v, mgl32.Vec4{0, 0, 1, 0}) {
+		t.Errorf("drone velocity %v before the mux update", v)
+	}
+	mux.Update()
+	if v := d.GetVelocity(); !approx(v, mgl32.Vec4{0, 0, 0, 1}) {
+		t.Errorf("drone velocity %v after the mux update", v)
+	}
+}
+
+func TestGamepadHandleButton(t *testing.T) {
+	c := DefaultGamepadConfig()
+	g, err := NewGamepad(c, DefaultKeyBindings())
+	if err != nil {
+		t.Fatal(err)
+	}
+	d := &fakeDriver{config: DefaultConfig()}
+
+	g.HandleButton("triangle_press", d)
+	if fd := d.FlightData(); !fd.EmSky {
+		t.Fatal("north did not take off")
+	}
+	g.HandleButton("l2_press", d)
+	g.HandleButton("x_press", d)
+	if fd := d.FlightData(); fd.EmSky {
+		t.Error("south did not land")
+	}
+}
@@ -0,0 +1,336 @@
+package drone
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-gl/mathgl/mgl32"
+	"gobot.io/x/gobot/platforms/keyboard"
+)
+
+const (
+	defaultKeyPower    = 40
+	defaultHoldTimeout = 500 * time.Millisecond
+)
+
+// Action is something a key can be bound to
+type Action func(d Drone)
+
+// KeyBindings maps keyboard keys to named actions. Its Handle method can be
+// given to New as the key handler.
+//
+// The keyboard only reports key presses (repeated while a key is held), so
+// motion keys set their axis and zero it again once no repeat arrived for the
+// hold timeout.
+type KeyBindings struct {
+	mutex sync.Mutex
+
+	actions     map[string]Action
+	keys        map[int]string
+	power       int
+	holdTimeout time.Duration
+	holdTimers  map[AxisMask]*time.Timer
+	source      *Source
+	takenoff    bool
+}
+
+// KeyConfig configures key bindings on top of the defaults
+type KeyConfig struct {
+	Power       int               `yaml:"power"`       // 0-100
+	HoldTimeout time.Duration     `yaml:"holdTimeout"` // zeroes a motion key after it
+	Bindings    map[string]string `yaml:"bindings"`    // key name -> action, empty unbinds
+}
+
+func DefaultKeyConfig() KeyConfig {
+	return KeyConfig{
+		Power:       defaultKeyPower,
+		HoldTimeout: defaultHoldTimeout,
+	}
+}
+
+// Validate checks the ranges and that every key and action name is known
+func (c KeyConfig) Validate() error {
+	if c.Power < 0 || c.Power > 100 {
+		return fmt.Errorf("key power %d not in [0, 100]", c.Power)
+	}
+	if c.HoldTimeout < 0 {
+		return fmt.Errorf("negative key hold timeout %v", c.HoldTimeout)
+	}
+	actions := DefaultKeyBindings().actions
+	for name, action := range c.Bindings {
+		if _, ok := keyNames[strings.ToLower(name)]; !ok {
+			return fmt.Errorf("unknown key %q", name)
+		}
+		if _, ok := actions[action]; action != "" && !ok {
+			return fmt.Errorf("unknown action %q", action)
+		}
+	}
+	return nil
+}
+
+var keyNames = map[string]int{
+	"space":  keyboard.Spacebar,
+	"escape": keyboard.Escape,
+	"up":     keyboard.ArrowUp,
+	"down":   keyboard.ArrowDown,
+	"left":   keyboard.ArrowLeft,
+	"right":  keyboard.ArrowRight,
+}
+
+func init() {
+	for i := 0; i < 26; i++ {
+		keyNames[string(rune('a'+i))] = keyboard.A + i
+	}
+	for i := 0; i < 10; i++ {
+		keyNames[string(rune('0'+i))] = keyboard.Zero + i
+	}
+}
+
+var defaultBindings = map[string]string{
+	"space":  "toggle-takeoff",
+	"y":      "throw-takeoff",
+	"p":      "palm-land",
+	"escape": "emergency",
+	"h":      "hover",
+	"w":      "forward",
+	"s":      "backward",
+	"a":      "left",
+	"d":      "right",
+	"up":     "up",
+	"down":   "down",
+	"left":   "counter-clockwise",
+	"right":  "clockwise",
+	"i":      "front-flip",
+	"k":      "back-flip",
+	"j":      "left-flip",
+	"l":      "right-flip",
+	"b":      "bounce",
+	"z":      "exposure-0",
+	"x":      "exposure-1",
+	"c":      "exposure-2",
+	"5":      "bitrate-auto",
+	"6":      "bitrate-1m",
+	"7":      "bitrate-2m",
+	"8":      "bitrate-3m",
+	"9":      "bitrate-4m",
+	"f":      "fast-mode",
+	"g":      "slow-mode",
+	"t":      "autopilot",
+	"n":      "next-target",
+	"v":      "auto-tune",
+	"m":      "precision-land",
+	"o":      "pause",
+	"q":      "abort",
+}
+
+// DefaultKeyBindings returns bindings for the whole Drone interface. The
+// autopilot, next-target, auto-tune, precision-land, pause and abort actions
+// do nothing until they are set with SetAction.
+func DefaultKeyBindings() *KeyBindings {
+	b := &KeyBindings{
+		actions:     make(map[string]Action),
+		keys:        make(map[int]string),
+		power:       defaultKeyPower,
+		holdTimeout: defaultHoldTimeout,
+		holdTimers:  make(map[AxisMask]*time.Timer),
+	}
+	b.registerDefaultActions()
+	for name, action := range defaultBindings {
+		b.keys[keyNames[name]] = action
+	}
+	return b
+}
+
+// NewKeyBindings returns the default bindings changed by the config
+func NewKeyBindings(config KeyConfig) (*KeyBindings, error) {
+	b := DefaultKeyBindings()
+	if config.Power > 0 {
+		b.power = config.Power
+	}
+	if config.HoldTimeout > 0 {
+		b.holdTimeout = config.HoldTimeout
+	}
+	for name, action := range config.Bindings {
+		if err := b.Bind(name, action); err != nil {
+			return nil, err
+		}
+	}
+	return b, nil
+}
+
+// Bind binds a key, given by name like "w", "space" or "up", to an action.
+// An empty action removes the binding.
+func (b *KeyBindings) Bind(keyName string, action string) error {
+	key, ok := keyNames[strings.ToLower(keyName)]
+	if !ok {
+		return fmt.Errorf("unknown key %q", keyName)
+	}
+
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	if action == "" {
+		delete(b.keys, key)
+		return nil
+	}
+	if _, ok := b.actions[action]; !ok {
+		return fmt.Errorf("unknown action %q", action)
+	}
+	b.keys[key] = action
+	return nil
+}
+
+// SetAction adds or replaces a named action
+func (b *KeyBindings) SetAction(name string, action Action) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	b.actions[name] = action
+}
+
+// SetSource routes motion keys through a command mux source instead of
+// driving the drone directly
+func (b *KeyBindings) SetSource(s *Source) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	b.source = s
+}
+
+// Handle runs the action bound to the key event
+func (b *KeyBindings) Handle(event keyboard.KeyEvent, d Drone) {
+	b.mutex.Lock()
+	action := b.actions[b.keys[event.Key]]
+	b.mutex.Unlock()
+
+	if action != nil {
+		action(d)
+	}
+}
+
+// Run runs a named action, it returns false if there is no such action. This
+// lets other inputs such as a gamepad share the same actions.
+func (b *KeyBindings) Run(name string, d Drone) bool {
+	b.mutex.Lock()
+	action, ok := b.actions[name]
+	b.mutex.Unlock()
+
+	if ok {
+		action(d)
+	}
+	return ok
+}
+
+func (b *KeyBindings) registerDefaultActions() {
+	b.actions["toggle-takeoff"] = func(d Drone) {
+		b.mutex.Lock()
+		takenoff := b.takenoff
+		b.takenoff = !b.takenoff
+		b.mutex.Unlock()
+
+		if takenoff {
+			d.Land()
+		} else {
+			d.TakeOff()
+		}
+	}
+	b.actions["takeoff"] = func(d Drone) { d.TakeOff() }
+	b.actions["land"] = func(d Drone) { d.Land() }
+	b.actions["throw-takeoff"] = func(d Drone) { d.ThrowTakeOff() }
+	b.actions["palm-land"] = func(d Drone) { d.PalmLand() }
+	b.actions["stop-landing"] = func(d Drone) { d.StopLanding() }
+	b.actions["emergency"] = func(d Drone) { d.Emergency() }
+	b.actions["hover"] = func(d Drone) {
+		// the mux would overwrite a hover of the drone with the next update
+		b.mutex.Lock()
+		source := b.source
+		b.mutex.Unlock()
+		if source != nil {
+			source.Set(mgl32.Vec4{})
+			return
+		}
+		d.Hover()
+		d.CeaseRotation()
+	}
+	b.actions["autopilot"] = func(d Drone) {}
+	b.actions["next-target"] = func(d Drone) {}
+	b.actions["auto-tune"] = func(d Drone) {}
+	b.actions["precision-land"] = func(d Drone) {}
+	b.actions["pause"] = func(d Drone) {}
+	b.actions["abort"] = func(d Drone) {}
+
+	b.actions["forward"] = b.motion(AxisForward, 1)
+	b.actions["backward"] = b.motion(AxisForward, -1)
+	b.actions["right"] = b.motion(AxisRight, 1)
+	b.actions["left"] = b.motion(AxisRight, -1)
+	b.actions["up"] = b.motion(AxisUp, 1)
+	b.actions["down"] = b.motion(AxisUp, -1)
+	b.actions["clockwise"] = b.motion(AxisClockwise, 1)
+	b.actions["counter-clockwise"] = b.motion(AxisClockwise, -1)
+
+	b.actions["front-flip"] = func(d Drone) { d.FrontFlip() }
+	b.actions["back-flip"] = func(d Drone) { d.BackFlip() }
+	b.actions["left-flip"] = func(d Drone) { d.LeftFlip() }
+	b.actions["right-flip"] = func(d Drone) { d.RightFlip() }
+	b.actions["bounce"] = func(d Drone) { d.Bounce() }
+
+	for level := 0; level <= 2; level++ {
+		level := level
+		b.actions[fmt.Sprintf("exposure-%d", level)] = func(d Drone) { d.SetExposure(level) }
+	}
+	for name, rate := range videoBitRates {
+		rate := rate
+		b.actions["bitrate-"+name] = func(d Drone) { d.SetVideoEncoderRate(rate) }
+	}
+	b.actions["fast-mode"] = func(d Drone) { d.SetFastMode() }
+	b.actions["slow-mode"] = func(d Drone) { d.SetSlowMode() }
+}
+
+// motion returns an action that drives one axis while the key is held
+func (b *KeyBindings) motion(axis AxisMask, sign int) Action {
+	return func(d Drone) {
+		b.mutex.Lock()
+		if b.source != nil {
+			d = b.source.Drone()
+		}
+		val := sign * b.power
+		if t, ok := b.holdTimers[axis]; ok {
+			t.Stop()
+		}
+		b.holdTimers[axis] = time.AfterFunc(b.holdTimeout, func() {
+			setAxis(d, axis, 0)
+		})
+		b.mutex.Unlock()
+
+		setAxis(d, axis, val)
+	}
+}
+
+// setAxis drives a single axis with -100 to 100
+func setAxis(d Drone, axis AxisMask, val int) {
+	switch axis {
+	case AxisRight:
+		if val >= 0 {
+			d.Right(val)
+		} else {
+			d.Left(-val)
+		}
+	case AxisUp:
+		if val >= 0 {
+			d.Up(val)
+		} else {
+			d.Down(-val)
+		}
+	case AxisForward:
+		if val >= 0 {
+			d.Forward(val)
+		} else {
+			d.Backward(-val)
+		}
+	case AxisClockwise:
+		if val >= 0 {
+			d.Clockwise(val)
+		} else {
+			d.CounterClockwise(-val)
+		}
+	}
+}
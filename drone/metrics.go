@@ -0,0 +1,25 @@
+package drone
+
+import (
+	"gobot.io/x/gobot/platforms/dji/tello"
+	"tellobot/metrics"
+)
+
+var (
+	framesDecoded = metrics.Default.NewCounter("tellobot_frames_decoded_total", "Video frames decoded.", "")
+	framesDropped = metrics.Default.NewCounter("tellobot_frames_dropped_total", "Video frames that could not be read or decoded.", "")
+	frameWait     = metrics.Default.NewHistogram("tellobot_frame_read_seconds", "Time spent waiting for a decoded video frame.", metrics.DefaultBuckets)
+	commands      = metrics.Default.NewCounter("tellobot_commands_total", "Movement commands sent to the drone.", "axis")
+	emergencies   = metrics.Default.NewCounter("tellobot_emergencies_total", "Emergency stops by the safety monitor.", "")
+	battery       = metrics.Default.NewGauge("tellobot_battery_percent", "Battery charge reported by the drone.")
+	wifiStrength  = metrics.Default.NewGauge("tellobot_wifi_strength", "Wifi signal strength reported by the drone.")
+)
+
+// recordFlightData updates the gauges from the telemetry
+func recordFlightData(fd *tello.FlightData) {
+	if fd == nil {
+		return
+	}
+	battery.Set(float64(fd.BatteryPercentage))
+	wifiStrength.Set(float64(fd.WifiStrength))
+}
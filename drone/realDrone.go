@@ -3,8 +3,11 @@ package drone
 import (
 	"fmt"
 	"io"
+	"net"
 	"os/exec"
 	"strconv"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/go-gl/mathgl/mgl32"
@@ -12,6 +15,7 @@ import (
 	"gobot.io/x/gobot/platforms/dji/tello"
 	"gocv.io/x/gocv"
 	"gocv.io/x/gocv/contrib"
+	"tellobot/logging"
 )
 
 type realDriver struct {
@@ -23,51 +27,64 @@ type realDriver struct {
 	frameBuf                  []byte
 	ffmpegOut                 io.ReadCloser
 	cameraToDrone             mgl32.Mat3
+	flightData                *tello.FlightData
+	flightDataMutex           sync.Mutex
+	config                    Config
 }
 
 const (
-	frameX    = 400
-	frameY    = 300
-	frameSize = frameX * frameY * 3
+	defaultFrameX = 400
+	defaultFrameY = 300
+
+	// the drone also accepts sdk text commands on its command port
+	sdkAddress = "192.168.10.1:8889"
+	sdkTimeout = 300 * time.Millisecond // for the reply to a command
+	sdkRetries = 3
 )
 
 func (d *realDriver) Init() error {
-	d.cameraToDrone = mgl32.Rotate3DX(mgl32.DegToRad(-13.0))
+	d.cameraToDrone = mgl32.Rotate3DX(mgl32.DegToRad(-d.config.CameraTilt))
 
 	// init ffmpeg
 	ffmpeg := exec.Command("ffmpeg", "-hwaccel", "auto", "-hwaccel_device", "opencl", "-i", "pipe:0",
-		"-pix_fmt", "bgr24", "-s", strconv.Itoa(frameX)+"x"+strconv.Itoa(frameY), "-f", "rawvideo", "pipe:1")
+		"-pix_fmt", "bgr24", "-s", strconv.Itoa(d.config.FrameWidth)+"x"+strconv.Itoa(d.config.FrameHeight), "-f", "rawvideo", "pipe:1")
 
 	ffmpegIn, _ := ffmpeg.StdinPipe()
 	d.ffmpegOut, _ = ffmpeg.StdoutPipe()
 
 	// init video buffer to hold the frame
-	d.frameBuf = make([]byte, frameSize)
+	d.frameBuf = make([]byte, d.config.FrameWidth*d.config.FrameHeight*3)
 
 	d.camMatrix, d.distCoeffs = contrib.ReadCameraParameters(d.cameraCalibrationFilename)
 
 	work := func() {
 		if err := ffmpeg.Start(); err != nil {
-			fmt.Println(err)
+			log.Error("ffmpeg", logging.Fields{"error": err})
 			return
 		}
 
 		d.On(tello.ConnectedEvent, func(data interface{}) {
-			fmt.Println("Connected")
+			log.Info("connected", nil)
 			d.StartVideo()
-			d.SetVideoEncoderRate(tello.VideoBitRate1M)
-			//d.SetVideoEncoderRate(tello.VideoBitRate4M)
-			d.SetExposure(1)
+			d.SetVideoEncoderRate(videoBitRates[d.config.BitRate])
+			d.SetExposure(d.config.Exposure)
 
 			gobot.Every(100*time.Millisecond, func() {
 				d.StartVideo()
 			})
 		})
 
+		d.On(tello.FlightDataEvent, func(data interface{}) {
+			d.flightDataMutex.Lock()
+			d.flightData = data.(*tello.FlightData)
+			d.flightDataMutex.Unlock()
+			recordFlightData(data.(*tello.FlightData))
+		})
+
 		d.On(tello.VideoFrameEvent, func(data interface{}) {
 			pkt := data.([]byte)
 			if _, err := ffmpegIn.Write(pkt); err != nil {
-				fmt.Println(err)
+				log.Error("video", logging.Fields{"error": err})
 			}
 		})
 	}
@@ -81,43 +98,103 @@ func (d *realDriver) Init() error {
 	return nil
 }
 
+// Emergency cuts the motors with the sdk text command, gobot's binary protocol
+// has no equivalent. Datagrams get lost, so it retries until the drone
+// acknowledges the command.
+func (d *realDriver) Emergency() error {
+	d.Hover()
+	d.CeaseRotation()
+
+	var err error
+	for i := 0; i < sdkRetries; i++ {
+		if err = sdkEmergency(sdkAddress, sdkTimeout); err == nil {
+			return nil
+		}
+		log.Warn("emergency not acknowledged", logging.Fields{"attempt": i + 1, "error": err})
+	}
+	return fmt.Errorf("emergency: %v", err)
+}
+
+// sdkEmergency enters the sdk mode and cuts the motors, each command has to
+// be answered with ok within the timeout
+func sdkEmergency(addr string, timeout time.Duration) error {
+	conn, err := net.Dial("udp", addr)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	for _, cmd := range []string{"command", "emergency"} {
+		if err := sdkCommand(conn, cmd, timeout); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func sdkCommand(conn net.Conn, cmd string, timeout time.Duration) error {
+	if _, err := conn.Write([]byte(cmd)); err != nil {
+		return err
+	}
+	if err := conn.SetReadDeadline(time.Now().Add(timeout)); err != nil {
+		return err
+	}
+	buf := make([]byte, 64)
+	n, err := conn.Read(buf)
+	if err != nil {
+		return fmt.Errorf("%s: %v", cmd, err)
+	}
+	if reply := strings.TrimSpace(string(buf[:n])); reply != "ok" {
+		return fmt.Errorf("%s: drone replied %q", cmd, reply)
+	}
+	return nil
+}
+
 func (d *realDriver) Right(val int) error {
 	d.velocity[0] = float32(val) / 100.0
+	commands.IncLabel("right")
 	return d.Driver.Right(val)
 }
 
 func (d *realDriver) Left(val int) error {
 	d.velocity[0] = float32(val) / 100.0 * -1
+	commands.IncLabel("right")
 	return d.Driver.Left(val)
 }
 
 func (d *realDriver) Up(val int) error {
 	d.velocity[1] = float32(val) / 100.0
+	commands.IncLabel("up")
 	return d.Driver.Up(val)
 }
 
 func (d *realDriver) Down(val int) error {
 	d.velocity[1] = float32(val) / 100.0 * -1
+	commands.IncLabel("up")
 	return d.Driver.Down(val)
 }
 
 func (d *realDriver) Forward(val int) error {
 	d.velocity[2] = float32(val) / 100.0
+	commands.IncLabel("forward")
 	return d.Driver.Forward(val)
 }
 
 func (d *realDriver) Backward(val int) error {
 	d.velocity[2] = float32(val) / 100.0 * -1
+	commands.IncLabel("forward")
 	return d.Driver.Backward(val)
 }
 
 func (d *realDriver) Clockwise(val int) error {
 	d.velocity[3] = float32(val) / 100.0
+	commands.IncLabel("clockwise")
 	return d.Driver.Clockwise(val)
 }
 
 func (d *realDriver) CounterClockwise(val int) error {
 	d.velocity[3] = float32(val) / 100.0 * -1
+	commands.IncLabel("clockwise")
 	return d.Driver.CounterClockwise(val)
 }
 
@@ -136,12 +213,26 @@ func (d *realDriver) GetVelocity() mgl32.Vec4 {
 	return d.velocity
 }
 
+func (d *realDriver) FlightData() *tello.FlightData {
+	d.flightDataMutex.Lock()
+	defer d.flightDataMutex.Unlock()
+	return d.flightData
+}
+
 func (d *realDriver) ReadVideoFrame(frame *gocv.Mat) error {
+	start := time.Now()
 	_, err := io.ReadFull(d.ffmpegOut, d.frameBuf)
 	if err != nil {
+		framesDropped.Inc()
+		return err
+	}
+	*frame, err = gocv.NewMatFromBytes(d.config.FrameHeight, d.config.FrameWidth, gocv.MatTypeCV8UC3, d.frameBuf)
+	if err != nil {
+		framesDropped.Inc()
 		return err
 	}
-	*frame, err = gocv.NewMatFromBytes(frameY, frameX, gocv.MatTypeCV8UC3, d.frameBuf)
+	framesDecoded.Inc()
+	frameWait.ObserveSince(start)
 	return nil
 }
 func (d *realDriver) CameraMatrix() *gocv.Mat {
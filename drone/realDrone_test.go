@@ -0,0 +1,88 @@
+package drone
+
+import (
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+// sdkServer answers sdk commands like the drone until it is closed, reply
+// returns the answer to a command or false to drop it
+func sdkServer(t *testing.T, reply func(cmd string) (string, bool)) (conn net.PacketConn, received chan string) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	received = make(chan string, 16)
+	go func() {
+		buf := make([]byte, 64)
+		for {
+			n, from, err := conn.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+			cmd := string(buf[:n])
+			received <- cmd
+			if answer, ok := reply(cmd); ok {
+				conn.WriteTo([]byte(answer), from)
+			}
+		}
+	}()
+	return conn, received
+}
+
+func commandsReceived(received chan string) []string {
+	var cmds []string
+	for {
+		select {
+		case cmd := <-received:
+			cmds = append(cmds, cmd)
+		default:
+			return cmds
+		}
+	}
+}
+
+func TestSdkEmergency(t *testing.T) {
+	server, received := sdkServer(t, func(cmd string) (string, bool) {
+		return "ok\r\n", true
+	})
+	defer server.Close()
+	if err := sdkEmergency(server.LocalAddr().String(), time.Second); err != nil {
+		t.Fatal(err)
+	}
+	if cmds := commandsReceived(received); strings.Join(cmds, ",") != "command,emergency" {
+		t.Errorf("drone received %v", cmds)
+	}
+}
+
+// emergency is only sent once the drone is in sdk mode
+func TestSdkEmergencyWaitsForCommand(t *testing.T) {
+	server, received := sdkServer(t, func(cmd string) (string, bool) {
+		return "error", true
+	})
+	defer server.Close()
+	if err := sdkEmergency(server.LocalAddr().String(), time.Second); err == nil {
+		t.Fatal("no error for a refused command")
+	}
+	if cmds := commandsReceived(received); strings.Join(cmds, ",") != "command" {
+		t.Errorf("drone received %v", cmds)
+	}
+}
+
+func TestSdkEmergencyTimeout(t *testing.T) {
+	server, _ := sdkServer(t, func(cmd string) (string, bool) {
+		return "ok", cmd == "command"
+	})
+	defer server.Close()
+	start := time.Now()
+	err := sdkEmergency(server.LocalAddr().String(), 50*time.Millisecond)
+	if err == nil || !strings.Contains(err.Error(), "emergency") {
+		t.Fatalf("got %v, want the unanswered emergency to fail", err)
+	}
+	if time.Since(start) > time.Second {
+		t.Errorf("took %v", time.Since(start))
+	}
+}
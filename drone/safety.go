@@ -0,0 +1,192 @@
+package drone
+
+import (
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/go-gl/mathgl/mgl32"
+	"gobot.io/x/gobot"
+	"gobot.io/x/gobot/platforms/dji/tello"
+	"tellobot/logging"
+)
+
+// EmergencyEvent is published with the reason when the safety monitor stops
+// the motors
+const EmergencyEvent = "emergency"
+
+const safetyPeriod = 50 * time.Millisecond
+
+// SafetyCondition reports a reason if the drone has to be stopped
+type SafetyCondition func(fd *tello.FlightData, d Drone) (reason string, stop bool)
+
+// SafetyConfig enables the built-in conditions, zero disables a condition
+type SafetyConfig struct {
+	MaxHeight    float32       `yaml:"maxHeight"`    // m
+	MaxClimbRate float32       `yaml:"maxClimbRate"` // m/s while not commanded to climb
+	ClimbTime    time.Duration `yaml:"climbTime"`    // how long the climb rate may be exceeded
+	MaxTilt      float32       `yaml:"maxTilt"`      // degrees, needs an attitude source
+}
+
+func DefaultSafetyConfig() SafetyConfig {
+	return SafetyConfig{
+		MaxHeight:    4.0,
+		MaxClimbRate: 1.0,
+		ClimbTime:    time.Second,
+		MaxTilt:      45,
+	}
+}
+
+func (c SafetyConfig) Validate() error {
+	if c.MaxHeight < 0 || c.MaxClimbRate < 0 || c.ClimbTime < 0 || c.MaxTilt < 0 {
+		return fmt.Errorf("negative safety limit")
+	}
+	if c.MaxTilt > 90 {
+		return fmt.Errorf("tilt limit %v above 90°", c.MaxTilt)
+	}
+	return nil
+}
+
+// Safety watches the flight data and calls Emergency on the drone directly,
+// bypassing any arbitration, as soon as a condition triggers
+type Safety struct {
+	gobot.Eventer
+
+	mutex      sync.Mutex
+	conditions []SafetyCondition
+	triggered  string
+}
+
+func NewSafety(config SafetyConfig, attitude func() (mgl32.Mat3, bool)) *Safety {
+	s := &Safety{Eventer: gobot.NewEventer()}
+	s.AddEvent(EmergencyEvent)
+
+	if config.MaxHeight > 0 {
+		s.AddCondition(MaxHeight(config.MaxHeight))
+	}
+	if config.MaxClimbRate > 0 {
+		s.AddCondition(RunawayAscent(config.MaxClimbRate, config.ClimbTime))
+	}
+	if config.MaxTilt > 0 && attitude != nil {
+		s.AddCondition(TiltLimit(config.MaxTilt, attitude))
+	}
+	return s
+}
+
+// AddCondition adds a custom condition
+func (s *Safety) AddCondition(c SafetyCondition) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.conditions = append(s.conditions, c)
+}
+
+// Start checks all conditions periodically
+func (s *Safety) Start(d Drone) {
+	gobot.Every(safetyPeriod, func() {
+		s.Check(d.FlightData(), d)
+	})
+}
+
+// Check evaluates all conditions once and stops the motors if one triggers.
+// It returns the reason or an empty string.
+func (s *Safety) Check(fd *tello.FlightData, d Drone) string {
+	s.mutex.Lock()
+	if s.triggered != "" {
+		s.mutex.Unlock()
+		return s.triggered
+	}
+	conditions := s.conditions
+	s.mutex.Unlock()
+
+	for _, c := range conditions {
+		reason, stop := c(fd, d)
+		if !stop {
+			continue
+		}
+
+		s.mutex.Lock()
+		s.triggered = reason
+		s.mutex.Unlock()
+
+		log.Error("emergency stop", logging.Fields{"reason": reason})
+		emergencies.Inc()
+		if err := d.Emergency(); err != nil {
+			log.Error("emergency stop failed", logging.Fields{"reason": reason, "error": err})
+		}
+		s.Publish(EmergencyEvent, reason)
+		return reason
+	}
+	return ""
+}
+
+// Triggered returns the reason of the emergency stop or an empty string
+func (s *Safety) Triggered() string {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.triggered
+}
+
+// Reset arms the monitor again after an emergency stop
+func (s *Safety) Reset() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.triggered = ""
+}
+
+// MaxHeight stops the drone above the given height in m
+func MaxHeight(height float32) SafetyCondition {
+	return func(fd *tello.FlightData, d Drone) (string, bool) {
+		if fd == nil {
+			return "", false
+		}
+		// telemetry height is in dm
+		h := float32(fd.Height) / 10
+		if h > height {
+			return fmt.Sprintf("height %.1fm above %.1fm", h, height), true
+		}
+		return "", false
+	}
+}
+
+// RunawayAscent stops the drone if it climbs faster than rate m/s for longer
+// than duration without being told to climb
+func RunawayAscent(rate float32, duration time.Duration) SafetyCondition {
+	var since time.Time
+	return func(fd *tello.FlightData, d Drone) (string, bool) {
+		if fd == nil {
+			return "", false
+		}
+		// vertical speed is in dm/s
+		climb := float32(fd.VerticalSpeed) / 10
+		if climb <= rate || d.GetVelocity()[1] > 0 {
+			since = time.Time{}
+			return "", false
+		}
+		if since.IsZero() {
+			since = time.Now()
+		}
+		if time.Since(since) > duration {
+			return fmt.Sprintf("runaway ascent at %.1fm/s", climb), true
+		}
+		return "", false
+	}
+}
+
+// TiltLimit stops the drone if it tilts more than the given degrees. The
+// attitude is the drone to world rotation of a world with y pointing down,
+// e.g. from the odometry.
+func TiltLimit(degrees float32, attitude func() (mgl32.Mat3, bool)) SafetyCondition {
+	return func(fd *tello.FlightData, d Drone) (string, bool) {
+		rot, ok := attitude()
+		if !ok {
+			return "", false
+		}
+		down := rot.Mul3x1(mgl32.Vec3{0, 1, 0})
+		tilt := mgl32.RadToDeg(float32(math.Acos(float64(mgl32.Clamp(down[1], -1, 1)))))
+		if tilt > degrees {
+			return fmt.Sprintf("tilt %.0f° above %.0f°", tilt, degrees), true
+		}
+		return "", false
+	}
+}
@@ -0,0 +1,129 @@
+package drone
+
+import (
+	"testing"
+	"time"
+
+	"github.com/go-gl/mathgl/mgl32"
+	"gobot.io/x/gobot/platforms/dji/tello"
+)
+
+// stubDrone reports fixed telemetry and counts emergency stops, every other
+// method panics
+type stubDrone struct {
+	Drone
+	flightData  *tello.FlightData
+	velocity    mgl32.Vec4
+	emergencies int
+}
+
+func (d *stubDrone) FlightData() *tello.FlightData { return d.flightData }
+func (d *stubDrone) GetVelocity() mgl32.Vec4       { return d.velocity }
+func (d *stubDrone) Emergency() error {
+	d.emergencies++
+	return nil
+}
+
+func TestSafetyMaxHeight(t *testing.T) {
+	s := NewSafety(SafetyConfig{MaxHeight: 2}, nil)
+	d := &stubDrone{}
+
+	for _, fd := range []*tello.FlightData{nil, {Height: 15}, {Height: 20}} {
+		if reason := s.Check(fd, d); reason != "" {
+			t.Errorf("height %v: stopped with %q", fd, reason)
+		}
+	}
+	if reason := s.Check(&tello.FlightData{Height: 25}, d); reason == "" || d.emergencies != 1 {
+		t.Fatalf("2.5m: reason %q, %d emergencies, want a stop", reason, d.emergencies)
+	}
+
+	// the stop is latched until the monitor is reset
+	if reason := s.Check(&tello.FlightData{Height: 10}, d); reason == "" || d.emergencies != 1 {
+		t.Errorf("after the stop: reason %q, %d emergencies, want the old reason only", reason, d.emergencies)
+	}
+	s.Reset()
+	if reason := s.Check(&tello.FlightData{Height: 10}, d); reason != "" || s.Triggered() != "" {
+		t.Errorf("after a reset: reason %q", reason)
+	}
+}
+
+func TestSafetyRunawayAscent(t *testing.T) {
+	s := NewSafety(SafetyConfig{MaxClimbRate: 1, ClimbTime: 50 * time.Millisecond}, nil)
+	climbing := &tello.FlightData{VerticalSpeed: 20}
+
+	// commanded climbs are fine however long they take
+	d := &stubDrone{velocity: mgl32.Vec4{0, 1, 0, 0}}
+	s.Check(climbing, d)
+	time.Sleep(60 * time.Millisecond)
+	if reason := s.Check(climbing, d); reason != "" {
+		t.Fatalf("commanded climb stopped with %q", reason)
+	}
+
+	d.velocity = mgl32.Vec4{}
+	if reason := s.Check(climbing, d); reason != "" {
+		t.Fatalf("stopped with %q before the climb time", reason)
+	}
+	time.Sleep(60 * time.Millisecond)
+	if reason := s.Check(climbing, d); reason == "" || d.emergencies != 1 {
+		t.Errorf("runaway ascent: reason %q, %d emergencies, want a stop", reason, d.emergencies)
+	}
+}
+
+func TestSafetyTiltLimit(t *testing.T) {
+	tests := []struct {
+		tilt  float32 // degrees around the x axis
+		known bool
+		stop  bool
+	}{
+		{tilt: 10, known: true},
+		{tilt: 60, known: true, stop: true},
+		{tilt: -60, known: true, stop: true},
+		{tilt: 60, known: false},
+	}
+	for _, test := range tests {
+		attitude := func() (mgl32.Mat3, bool) {
+			return mgl32.Rotate3DX(mgl32.DegToRad(test.tilt)), test.known
+		}
+		s := NewSafety(SafetyConfig{MaxTilt: 45}, attitude)
+		d := &stubDrone{}
+		reason := s.Check(&tello.FlightData{}, d)
+		if (reason != "") != test.stop || d.emergencies > 1 {
+			t.Errorf("tilt %v, known %v: reason %q, want stop %v", test.tilt, test.known, reason, test.stop)
+		}
+	}
+}
+
+func TestSafetyCustomCondition(t *testing.T) {
+	s := NewSafety(SafetyConfig{}, nil)
+	s.AddCondition(func(fd *tello.FlightData, d Drone) (string, bool) {
+		return "battery", fd.BatteryPercentage < 10
+	})
+	d := &stubDrone{}
+	if reason := s.Check(&tello.FlightData{BatteryPercentage: 50}, d); reason != "" {
+		t.Errorf("stopped with %q", reason)
+	}
+	if reason := s.Check(&tello.FlightData{BatteryPercentage: 5}, d); reason != "battery" || s.Triggered() != "battery" {
+		t.Errorf("got %q, want battery", reason)
+	}
+}
+
+// the fake driver simulates the height, so the monitor stops it like the
+// real drone
+func TestSafetyStopsFakeDrone(t *testing.T) {
+	d := &fakeDriver{config: DefaultConfig()}
+	d.TakeOff()
+	s := NewSafety(SafetyConfig{MaxHeight: 2}, nil)
+
+	if reason := s.Check(d.FlightData(), d); reason != "" {
+		t.Fatalf("stopped at take off with %q", reason)
+	}
+	d.stateMutex.Lock()
+	d.height = 2.5
+	d.stateMutex.Unlock()
+	if reason := s.Check(d.FlightData(), d); reason == "" {
+		t.Fatal("no stop at 2.5m")
+	}
+	if fd := d.FlightData(); fd.EmSky || fd.Height != 0 {
+		t.Errorf("still flying after the stop at %vdm", fd.Height)
+	}
+}
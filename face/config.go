@@ -0,0 +1,50 @@
+package face
+
+import "fmt"
+
+// Config selects the detector network and how faces are turned into
+// targets. Any SSD style network works, its output has to be the usual
+// 1x1xNx7 detections.
+type Config struct {
+	Model     string     `yaml:"model"`     // .caffemodel or .onnx
+	NetConfig string     `yaml:"netConfig"` // .prototxt for caffe models, empty for onnx
+	Backend   string     `yaml:"backend"`   // see dnn.ReadNet
+	Target    string     `yaml:"target"`    // see dnn.ReadNet
+	InputSize int        `yaml:"inputSize"` // pixels, the network input is square
+	Scale     float64    `yaml:"scale"`     // applied to the pixel values after subtracting the mean
+	Mean      [3]float64 `yaml:"mean"`      // BGR
+	SwapRB    bool       `yaml:"swapRB"`    // the network expects RGB
+
+	MinConfidence float32 `yaml:"minConfidence"` // detections below it are dropped
+	Width         float32 `yaml:"width"`         // m, of an average face, for the range
+}
+
+func DefaultConfig() Config {
+	return Config{
+		Model:         "res10_300x300_ssd_iter_140000.caffemodel",
+		NetConfig:     "deploy.prototxt.txt",
+		Backend:       "default",
+		Target:        "cpu",
+		InputSize:     300,
+		Scale:         1.0,
+		Mean:          [3]float64{104, 177, 123},
+		MinConfidence: 0.5,
+		Width:         0.15,
+	}
+}
+
+func (c Config) Validate() error {
+	if c.Model == "" {
+		return fmt.Errorf("no model")
+	}
+	if c.InputSize <= 0 || c.Scale <= 0 {
+		return fmt.Errorf("invalid input size %d or scale %v", c.InputSize, c.Scale)
+	}
+	if c.MinConfidence < 0 || c.MinConfidence > 1 {
+		return fmt.Errorf("min confidence %v not in [0, 1]", c.MinConfidence)
+	}
+	if c.Width <= 0 {
+		return fmt.Errorf("invalid face width %v", c.Width)
+	}
+	return nil
+}
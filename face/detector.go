@@ -0,0 +1,102 @@
+package face
+
+import (
+	"image"
+	"sort"
+
+	"github.com/go-gl/mathgl/mgl32"
+	"gocv.io/x/gocv"
+	"tellobot/dnn"
+	"tellobot/drone"
+	"tellobot/tracking"
+)
+
+// Detector finds faces with a dnn
+type Detector struct {
+	net    gocv.Net
+	config Config
+}
+
+// NewDetector loads the network, caffe or onnx is chosen by the file
+// extension of the model
+func NewDetector(config Config) (*Detector, error) {
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+	net, err := dnn.ReadNet(config.Model, config.NetConfig, config.Backend, config.Target)
+	if err != nil {
+		return nil, err
+	}
+	return &Detector{net: net, config: config}, nil
+}
+
+func (d *Detector) Close() error {
+	return d.net.Close()
+}
+
+// Detect returns all faces above the minimum confidence, the most confident
+// first
+func (d *Detector) Detect(frame *gocv.Mat) []tracking.Detection {
+	W := float32(frame.Cols())
+	H := float32(frame.Rows())
+	c := d.config
+
+	blob := gocv.BlobFromImage(*frame, c.Scale, image.Pt(c.InputSize, c.InputSize),
+		gocv.NewScalar(c.Mean[0], c.Mean[1], c.Mean[2], 0), c.SwapRB, false)
+	defer blob.Close()
+
+	// the unnamed input and output work for caffe and onnx models alike
+	d.net.SetInput(blob, "")
+	detBlob := d.net.Forward("")
+	defer detBlob.Close()
+
+	detections := gocv.GetBlobChannel(detBlob, 0, 0)
+	defer detections.Close()
+
+	var faces []tracking.Detection
+	for r := 0; r < detections.Rows(); r++ {
+		confidence := detections.GetFloatAt(r, 2)
+		if confidence < c.MinConfidence {
+			continue
+		}
+
+		left := mgl32.Clamp(detections.GetFloatAt(r, 3)*W, 0, W-1)
+		top := mgl32.Clamp(detections.GetFloatAt(r, 4)*H, 0, H-1)
+		right := mgl32.Clamp(detections.GetFloatAt(r, 5)*W, 0, W-1)
+		bottom := mgl32.Clamp(detections.GetFloatAt(r, 6)*H, 0, H-1)
+		box := image.Rect(int(left), int(top), int(right), int(bottom))
+		if box.Empty() {
+			continue
+		}
+		faces = append(faces, tracking.Detection{Box: box, Confidence: confidence})
+	}
+	sort.Slice(faces, func(i, j int) bool { return faces[i].Confidence > faces[j].Confidence })
+	return faces
+}
+
+// Faces returns all faces with their positions, estimated from the face
+// width and the camera calibration of the drone
+func (d *Detector) Faces(dr drone.Drone) tracking.MultiTarget {
+	return tracking.MultiTargetFunc(func(frame *gocv.Mat) []tracking.Detection {
+		faces := d.Detect(frame)
+		for i := range faces {
+			if pos, ok := tracking.Locate(faces[i].Box, d.config.Width, dr.CameraMatrix()); ok {
+				faces[i].HasPose = true
+				faces[i].Position = dr.CameraToDroneMatrix().Mul3x1(pos)
+			}
+		}
+		return faces
+	})
+}
+
+// Target follows the most confident face
+func (d *Detector) Target(dr drone.Drone) tracking.Target {
+	faces := d.Faces(dr)
+	return tracking.TargetFunc(func(frame *gocv.Mat) (tracking.Detection, bool) {
+		all := faces.DetectAll(frame)
+		if len(all) == 0 {
+			return tracking.Detection{}, false
+		}
+		return all[0], true
+	})
+}
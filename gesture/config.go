@@ -0,0 +1,60 @@
+package gesture
+
+import (
+	"fmt"
+	"time"
+)
+
+// Config selects the pose network and which action a gesture runs. The
+// network has to output OpenPose style COCO heatmaps, 18 keypoints first.
+type Config struct {
+	Model     string  `yaml:"model"`     // .onnx
+	Backend   string  `yaml:"backend"`   // see dnn.ReadNet
+	Target    string  `yaml:"target"`    // see dnn.ReadNet
+	InputSize int     `yaml:"inputSize"` // pixels, the network input is square
+	Threshold float32 `yaml:"threshold"` // keypoints below it are not seen
+
+	Frames   int           `yaml:"frames"`   // frames in a row a gesture has to be seen
+	Cooldown time.Duration `yaml:"cooldown"` // before a gesture runs its action again
+
+	// Actions maps gestures to key binding actions, an empty action ignores
+	// the gesture. Motion actions such as clockwise keep running while the
+	// gesture is held.
+	Actions map[string]string `yaml:"actions"`
+}
+
+func DefaultConfig() Config {
+	return Config{
+		Model:     "human-pose-estimation.onnx",
+		Backend:   "default",
+		Target:    "cpu",
+		InputSize: 256,
+		Threshold: 0.1,
+		Frames:    5,
+		Cooldown:  2 * time.Second,
+		Actions: map[string]string{
+			string(Palm):       "hover",
+			string(ArmsUp):     "land",
+			string(PointLeft):  "counter-clockwise",
+			string(PointRight): "clockwise",
+		},
+	}
+}
+
+func (c Config) Validate() error {
+	if c.Model == "" {
+		return fmt.Errorf("no model")
+	}
+	if c.InputSize <= 0 || c.Threshold < 0 || c.Threshold > 1 {
+		return fmt.Errorf("invalid input size %d or threshold %v", c.InputSize, c.Threshold)
+	}
+	if c.Frames < 1 || c.Cooldown < 0 {
+		return fmt.Errorf("invalid debounce frames %d or cooldown %v", c.Frames, c.Cooldown)
+	}
+	for g := range c.Actions {
+		if !known(Gesture(g)) {
+			return fmt.Errorf("unknown gesture %q", g)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,183 @@
+package gesture
+
+import (
+	"time"
+
+	"gocv.io/x/gocv"
+	"tellobot/drone"
+	"tellobot/logging"
+)
+
+var log = logging.Default.With("gesture")
+
+// Gesture is a body pose that controls the drone
+type Gesture string
+
+const (
+	None       Gesture = ""
+	Palm       Gesture = "palm"        // one hand raised
+	ArmsUp     Gesture = "arms-up"     // both hands above the head
+	PointLeft  Gesture = "point-left"  // an arm stretched out to the left of the image
+	PointRight Gesture = "point-right" // an arm stretched out to the right of the image
+)
+
+func known(g Gesture) bool {
+	switch g {
+	case Palm, ArmsUp, PointLeft, PointRight:
+		return true
+	}
+	return false
+}
+
+// motions are the key binding actions that only move while they are repeated
+var motions = map[string]bool{
+	"forward": true, "backward": true,
+	"left": true, "right": true,
+	"up": true, "down": true,
+	"clockwise": true, "counter-clockwise": true,
+}
+
+// Classify returns the gesture of a pose, distances are relative to the
+// shoulder width so it works at any range
+func Classify(p Pose) Gesture {
+	if !p.Seen(RightShoulder, LeftShoulder) {
+		return None
+	}
+	rs, ls := p[RightShoulder], p[LeftShoulder]
+	width := ls.X - rs.X
+	if width < 0 {
+		width = -width
+	}
+	if width == 0 {
+		return None
+	}
+
+	// above the head, or a shoulder width above the shoulders without a nose
+	head := (rs.Y+ls.Y)/2 - width
+	if p.Seen(Nose) {
+		head = p[Nose].Y
+	}
+	above := func(wrist int) bool {
+		return p.Seen(wrist) && p[wrist].Y < head
+	}
+	raised := func(wrist int, shoulder int) bool {
+		return p.Seen(wrist) && p[wrist].Y < p[shoulder].Y
+	}
+	// stretched returns -1 or 1 for an arm pointing left or right in the
+	// image, 0 if it does not point
+	stretched := func(wrist int, shoulder int) int {
+		if !p.Seen(wrist) {
+			return 0
+		}
+		dx := p[wrist].X - p[shoulder].X
+		dy := p[wrist].Y - p[shoulder].Y
+		if dy > width/2 || dy < -width/2 {
+			return 0
+		}
+		switch {
+		case dx > width:
+			return 1
+		case dx < -width:
+			return -1
+		}
+		return 0
+	}
+
+	if above(RightWrist) && above(LeftWrist) {
+		return ArmsUp
+	}
+	switch stretched(RightWrist, RightShoulder) + stretched(LeftWrist, LeftShoulder) {
+	case -1, -2:
+		return PointLeft
+	case 1, 2:
+		return PointRight
+	}
+	if raised(RightWrist, RightShoulder) != raised(LeftWrist, LeftShoulder) {
+		return Palm
+	}
+	return None
+}
+
+// Debouncer only passes a gesture once it was seen for a number of frames
+// in a row
+type Debouncer struct {
+	frames    int
+	candidate Gesture
+	count     int
+}
+
+func NewDebouncer(frames int) *Debouncer {
+	return &Debouncer{frames: frames}
+}
+
+// Update returns the gesture while it is stable, None otherwise
+func (d *Debouncer) Update(g Gesture) Gesture {
+	if g != d.candidate {
+		d.candidate = g
+		d.count = 0
+	}
+	d.count++
+	if d.count < d.frames {
+		return None
+	}
+	return g
+}
+
+// Actions runs named actions, *drone.KeyBindings implements it
+type Actions interface {
+	Run(name string, d drone.Drone) bool
+}
+
+// Controller runs the action of a stable gesture. Motion actions run every
+// frame while the gesture is held, other actions once per cooldown.
+type Controller struct {
+	estimator *Estimator
+	config    Config
+	debouncer *Debouncer
+	actions   Actions
+
+	last    Gesture
+	lastRun time.Time
+}
+
+func NewController(e *Estimator, config Config, actions Actions) *Controller {
+	return &Controller{
+		estimator: e,
+		config:    config,
+		debouncer: NewDebouncer(config.Frames),
+		actions:   actions,
+	}
+}
+
+// Update estimates the pose in the frame and runs the action of its gesture
+// on the drone. It returns the pose and the stable gesture.
+func (c *Controller) Update(frame *gocv.Mat, d drone.Drone) (Pose, Gesture) {
+	pose := c.estimator.Estimate(frame)
+	return pose, c.handle(pose, d, time.Now())
+}
+
+// handle runs the action of the stable gesture of a pose
+func (c *Controller) handle(pose Pose, d drone.Drone, now time.Time) Gesture {
+	g := c.debouncer.Update(Classify(pose))
+
+	previous := c.last
+	c.last = g
+	action := c.config.Actions[string(g)]
+	if g == None || action == "" {
+		return g
+	}
+
+	if !motions[action] {
+		if g == previous || now.Sub(c.lastRun) < c.config.Cooldown {
+			return g
+		}
+		c.lastRun = now
+	}
+	if g != previous {
+		log.Info("gesture", logging.Fields{"gesture": g, "action": action})
+	}
+	if !c.actions.Run(action, d) {
+		log.Warn("unknown action", logging.Fields{"gesture": g, "action": action})
+	}
+	return g
+}
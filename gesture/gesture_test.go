@@ -0,0 +1,148 @@
+package gesture
+
+import (
+	"image"
+	"testing"
+	"time"
+
+	"tellobot/drone"
+)
+
+// person returns a pose facing the camera with shoulders 40 pixels apart at
+// y 100, the nose above and the given wrists. A wrist at the zero point is
+// not seen.
+func person(right image.Point, left image.Point) Pose {
+	var p Pose
+	seen := func(part int, pt image.Point) {
+		if pt != (image.Point{}) {
+			p[part] = Keypoint{pt, 1}
+		}
+	}
+	seen(Nose, image.Pt(100, 70))
+	seen(RightShoulder, image.Pt(80, 100))
+	seen(LeftShoulder, image.Pt(120, 100))
+	seen(RightWrist, right)
+	seen(LeftWrist, left)
+	return p
+}
+
+var hanging = struct{ right, left image.Point }{image.Pt(80, 160), image.Pt(120, 160)}
+
+func TestClassify(t *testing.T) {
+	noNose := person(image.Pt(80, 50), image.Pt(120, 50))
+	noNose[Nose] = Keypoint{}
+	lowNoNose := person(image.Pt(80, 65), image.Pt(120, 65))
+	lowNoNose[Nose] = Keypoint{}
+	noShoulder := person(image.Pt(80, 50), image.Pt(120, 50))
+	noShoulder[LeftShoulder] = Keypoint{}
+
+	tests := []struct {
+		name string
+		pose Pose
+		want Gesture
+	}{
+		{"nobody", Pose{}, None},
+		{"arms hanging", person(hanging.right, hanging.left), None},
+		{"right hand raised", person(image.Pt(80, 80), hanging.left), Palm},
+		{"left hand raised", person(hanging.right, image.Pt(120, 80)), Palm},
+		{"raised hand without the other wrist", person(image.Pt(80, 80), image.Point{}), Palm},
+		{"both hands at the face", person(image.Pt(80, 80), image.Pt(120, 80)), None},
+		{"arms up", person(image.Pt(80, 40), image.Pt(120, 40)), ArmsUp},
+		{"arms up without a nose", noNose, ArmsUp},
+		{"hands below the estimated head", lowNoNose, None},
+		{"right arm to the left", person(image.Pt(20, 100), hanging.left), PointLeft},
+		{"left arm to the right", person(hanging.right, image.Pt(180, 110)), PointRight},
+		{"arm stretched too high", person(hanging.right, image.Pt(180, 70)), Palm},
+		{"both arms stretched", person(image.Pt(20, 100), image.Pt(180, 100)), None},
+		{"one shoulder", noShoulder, None},
+	}
+	for _, test := range tests {
+		if g := Classify(test.pose); g != test.want {
+			t.Errorf("%s: got %q, want %q", test.name, g, test.want)
+		}
+	}
+}
+
+func TestDebouncer(t *testing.T) {
+	d := NewDebouncer(3)
+	steps := []struct {
+		in, want Gesture
+	}{
+		{Palm, None},
+		{Palm, None},
+		{Palm, Palm},
+		{Palm, Palm},
+		// a single other frame starts over
+		{ArmsUp, None},
+		{Palm, None},
+		{Palm, None},
+		{Palm, Palm},
+		{None, None},
+	}
+	for i, step := range steps {
+		if g := d.Update(step.in); g != step.want {
+			t.Errorf("step %d: got %q, want %q", i, g, step.want)
+		}
+	}
+}
+
+// recorder records the actions it is asked to run
+type recorder struct {
+	runs []string
+}
+
+func (r *recorder) Run(name string, d drone.Drone) bool {
+	r.runs = append(r.runs, name)
+	return true
+}
+
+func TestControllerCooldown(t *testing.T) {
+	c := DefaultConfig()
+	c.Frames = 2
+	c.Cooldown = time.Second
+	r := &recorder{}
+	controller := NewController(nil, c, r)
+
+	palm := person(image.Pt(80, 80), hanging.left)
+	left := person(image.Pt(20, 100), hanging.left)
+	rest := person(hanging.right, hanging.left)
+
+	now := time.Unix(0, 0)
+	steps := []struct {
+		pose  Pose
+		after time.Duration
+		runs  int // actions run so far
+	}{
+		{palm, 0, 0},
+		{palm, 100 * time.Millisecond, 1},
+		// holding the gesture runs it once
+		{palm, 100 * time.Millisecond, 1},
+		{rest, 100 * time.Millisecond, 1},
+		{rest, 100 * time.Millisecond, 1},
+		// again within the cooldown
+		{palm, 100 * time.Millisecond, 1},
+		{palm, 100 * time.Millisecond, 1},
+		{rest, 100 * time.Millisecond, 1},
+		// after the cooldown
+		{palm, time.Second, 1},
+		{palm, 100 * time.Millisecond, 2},
+		// motions repeat every frame while held
+		{left, 100 * time.Millisecond, 2},
+		{left, 100 * time.Millisecond, 3},
+		{left, 100 * time.Millisecond, 4},
+	}
+	for i, step := range steps {
+		now = now.Add(step.after)
+		controller.handle(step.pose, nil, now)
+		if len(r.runs) != step.runs {
+			t.Fatalf("step %d: ran %v, want %d actions", i, r.runs, step.runs)
+		}
+	}
+	want := []string{"hover", "hover", "counter-clockwise", "counter-clockwise"}
+	for i := range want {
+		if r.runs[i] != want[i] {
+			t.Errorf("ran %v, want %v", r.runs, want)
+			break
+		}
+	}
+}
@@ -0,0 +1,102 @@
+package gesture
+
+import (
+	"image"
+
+	"gocv.io/x/gocv"
+	"tellobot/dnn"
+)
+
+// Keypoint indices of the COCO body model
+const (
+	Nose = iota
+	Neck
+	RightShoulder
+	RightElbow
+	RightWrist
+	LeftShoulder
+	LeftElbow
+	LeftWrist
+	RightHip
+	RightKnee
+	RightAnkle
+	LeftHip
+	LeftKnee
+	LeftAnkle
+	RightEye
+	LeftEye
+	RightEar
+	LeftEar
+
+	keypoints
+)
+
+// Keypoint is a body part in image coordinates
+type Keypoint struct {
+	image.Point
+	Confidence float32
+}
+
+// Pose are the keypoints of one person, left and right are the ones of the
+// person, so the right arm appears on the left of the image
+type Pose [keypoints]Keypoint
+
+// Estimator finds the pose of a single person with a dnn
+type Estimator struct {
+	net    gocv.Net
+	config Config
+}
+
+func NewEstimator(config Config) (*Estimator, error) {
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+	net, err := dnn.ReadNet(config.Model, "", config.Backend, config.Target)
+	if err != nil {
+		return nil, err
+	}
+	return &Estimator{net: net, config: config}, nil
+}
+
+func (e *Estimator) Close() error {
+	return e.net.Close()
+}
+
+// Estimate returns the strongest location of every keypoint, keypoints below
+// the threshold have no confidence
+func (e *Estimator) Estimate(frame *gocv.Mat) Pose {
+	size := e.config.InputSize
+	blob := gocv.BlobFromImage(*frame, 1.0/255, image.Pt(size, size), gocv.NewScalar(0, 0, 0, 0), false, false)
+	defer blob.Close()
+
+	e.net.SetInput(blob, "")
+	heatmaps := e.net.Forward("")
+	defer heatmaps.Close()
+
+	var pose Pose
+	for i := range pose {
+		heatmap := gocv.GetBlobChannel(heatmaps, 0, i)
+		_, confidence, _, loc := gocv.MinMaxLoc(heatmap)
+		cols, rows := heatmap.Cols(), heatmap.Rows()
+		heatmap.Close()
+
+		if confidence < e.config.Threshold || cols == 0 || rows == 0 {
+			continue
+		}
+		pose[i] = Keypoint{
+			Point:      image.Pt(loc.X*frame.Cols()/cols, loc.Y*frame.Rows()/rows),
+			Confidence: confidence,
+		}
+	}
+	return pose
+}
+
+// Seen returns whether all keypoints were found
+func (p *Pose) Seen(parts ...int) bool {
+	for _, i := range parts {
+		if p[i].Confidence == 0 {
+			return false
+		}
+	}
+	return true
+}
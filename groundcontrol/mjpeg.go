@@ -0,0 +1,120 @@
+package groundcontrol
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"gocv.io/x/gocv"
+)
+
+const (
+	defaultQuality = 75
+	defaultFPS     = 10
+	maxFPS         = 30
+)
+
+// FrameStream serves the latest published frame as MJPEG over http. Every
+// client picks its own quality and frame rate with the quality (1-100) and
+// fps query parameters, e.g. /video/annotated?quality=50&fps=5
+type FrameStream struct {
+	mutex   sync.Mutex
+	frame   gocv.Mat
+	updated chan struct{}
+}
+
+func NewFrameStream() *FrameStream {
+	return &FrameStream{
+		frame:   gocv.NewMat(),
+		updated: make(chan struct{}),
+	}
+}
+
+func (f *FrameStream) Close() {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	f.frame.Close()
+}
+
+// Publish makes img the current frame, img is copied
+func (f *FrameStream) Publish(img gocv.Mat) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	img.CopyTo(&f.frame)
+
+	// wake up all waiting clients
+	close(f.updated)
+	f.updated = make(chan struct{})
+}
+
+// next waits for a new frame and returns it encoded as jpeg
+func (f *FrameStream) next(r *http.Request, quality int) ([]byte, error) {
+	f.mutex.Lock()
+	updated := f.updated
+	f.mutex.Unlock()
+
+	select {
+	case <-updated:
+	case <-r.Context().Done():
+		return nil, r.Context().Err()
+	}
+
+	f.mutex.Lock()
+	img := f.frame.Clone()
+	f.mutex.Unlock()
+	defer img.Close()
+
+	return gocv.IMEncodeWithParams(gocv.JPEGFileExt, img, []int{int(gocv.IMWriteJpegQuality), quality})
+}
+
+func (f *FrameStream) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	quality := queryInt(r, "quality", defaultQuality, 1, 100)
+	fps := queryInt(r, "fps", defaultFPS, 1, maxFPS)
+	period := time.Second / time.Duration(fps)
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming not supported", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "multipart/x-mixed-replace; boundary=frame")
+	w.Header().Set("Cache-Control", "no-cache")
+
+	for {
+		start := time.Now()
+
+		buf, err := f.next(r, quality)
+		if err != nil {
+			return
+		}
+
+		fmt.Fprintf(w, "--frame\r\nContent-Type: image/jpeg\r\nContent-Length: %d\r\n\r\n", len(buf))
+		if _, err := w.Write(buf); err != nil {
+			return
+		}
+		fmt.Fprint(w, "\r\n")
+		flusher.Flush()
+
+		if wait := period - time.Since(start); wait > 0 {
+			time.Sleep(wait)
+		}
+	}
+}
+
+func queryInt(r *http.Request, name string, def int, min int, max int) int {
+	v, err := strconv.Atoi(r.URL.Query().Get(name))
+	if err != nil {
+		return def
+	}
+	if v < min {
+		return min
+	}
+	if v > max {
+		return max
+	}
+	return v
+}
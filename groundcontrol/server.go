@@ -0,0 +1,390 @@
+package groundcontrol
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"mime"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-gl/mathgl/mgl32"
+	"github.com/gorilla/websocket"
+	"gobot.io/x/gobot/platforms/dji/tello"
+	"tellobot/drone"
+	"tellobot/localization"
+	"tellobot/logging"
+	"tellobot/race"
+)
+
+var log = logging.Default.With("groundcontrol")
+
+const (
+	telemetryPeriod = 100 * time.Millisecond
+	writeTimeout    = time.Second
+)
+
+// Detection is a ring seen in the last frame
+type Detection struct {
+	ID       int        `json:"id"`
+	Position mgl32.Vec3 `json:"position"` // camera coordinates (m)
+	Markers  int        `json:"markers"`  // markers detected or tracked
+}
+
+// State is everything a ground station gets to see
+type State struct {
+	Time       time.Time          `json:"time"`
+	Mode       string             `json:"mode"`
+	Modes      []string           `json:"modes"`
+	FlightData *tello.FlightData  `json:"flightData"`
+	Velocity   mgl32.Vec4         `json:"velocity"`
+	Pose       *localization.Pose `json:"pose,omitempty"`
+	Rings      []Detection        `json:"rings"`
+}
+
+// Command is sent by a ground station to the command endpoint or the
+// websocket. Command is one of takeoff, land, hover, emergency, velocity or
+// mode.
+type Command struct {
+	Command  string     `json:"command"`
+	Velocity mgl32.Vec4 `json:"velocity"` // for velocity, like GetVelocity
+	Mode     string     `json:"mode"`     // for mode
+}
+
+// Server exposes drone state, telemetry and detections over http and streams
+// them over a websocket. It works with any drone.
+type Server struct {
+	drone drone.Drone
+	mux   *http.ServeMux
+
+	mutex       sync.Mutex
+	source      *drone.Source
+	mode        string
+	modes       []string
+	modeHandler func(mode string) error
+	token       string
+	pose        *localization.Pose
+	rings       []Detection
+	clients     map[*websocket.Conn]bool
+
+	upgrader websocket.Upgrader
+}
+
+func NewServer(d drone.Drone) *Server {
+	s := &Server{
+		drone:   d,
+		mux:     http.NewServeMux(),
+		clients: make(map[*websocket.Conn]bool),
+	}
+	// browsers send cross site websocket requests too, so only pages served
+	// by this server may connect
+	s.upgrader.CheckOrigin = sameOrigin
+
+	s.mux.HandleFunc("/api/state", s.handleState)
+	s.mux.HandleFunc("/api/telemetry", s.handleTelemetry)
+	s.mux.HandleFunc("/api/rings", s.handleRings)
+	s.mux.HandleFunc("/api/command", s.handleCommand)
+	s.mux.HandleFunc("/api/ws", s.handleWebSocket)
+
+	return s
+}
+
+// Handle adds another handler to the server
+func (s *Server) Handle(pattern string, handler http.Handler) {
+	s.mux.Handle(pattern, handler)
+}
+
+// ListenAndServe serves until an error occurs
+func (s *Server) ListenAndServe(addr string) error {
+	go s.broadcast()
+	log.Info("listening", logging.Fields{"address": addr})
+	return http.ListenAndServe(addr, s.mux)
+}
+
+// SetSource routes velocity commands through a command mux source
+func (s *Server) SetSource(source *drone.Source) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.source = source
+}
+
+// SetToken requires commands to carry the token, either as a bearer token or
+// as the token query parameter, which is the only way for browser websockets
+func (s *Server) SetToken(token string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.token = token
+}
+
+// authorized checks the token and the origin of a request that controls the
+// drone
+func (s *Server) authorized(r *http.Request) error {
+	s.mutex.Lock()
+	token := s.token
+	s.mutex.Unlock()
+	return authorize(r, token)
+}
+
+func authorize(r *http.Request, token string) error {
+	if !sameOrigin(r) {
+		return fmt.Errorf("cross origin request from %s", r.Header.Get("Origin"))
+	}
+	if token == "" {
+		return nil
+	}
+	given := r.URL.Query().Get("token")
+	if auth := r.Header.Get("Authorization"); strings.HasPrefix(auth, "Bearer ") {
+		given = strings.TrimPrefix(auth, "Bearer ")
+	}
+	if subtle.ConstantTimeCompare([]byte(given), []byte(token)) != 1 {
+		return fmt.Errorf("invalid token")
+	}
+	return nil
+}
+
+// isJSON rejects forms and text/plain, which can be posted cross site
+// without a preflight
+func isJSON(r *http.Request) bool {
+	t, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	return err == nil && t == "application/json"
+}
+
+// Guard lets requests that change something through to h only if they are
+// authorized like the commands and carry JSON, reads pass as they are. It
+// protects handlers mounted next to the server or on a server of their own.
+func Guard(token string, h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			if err := authorize(r, token); err != nil {
+				http.Error(w, err.Error(), http.StatusForbidden)
+				return
+			}
+			if !isJSON(r) {
+				http.Error(w, "use Content-Type application/json", http.StatusUnsupportedMediaType)
+				return
+			}
+		}
+		h.ServeHTTP(w, r)
+	})
+}
+
+// sameOrigin accepts requests from pages served by this host and those
+// without an origin, which only come from clients other than browsers
+func sameOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	u, err := url.Parse(origin)
+	return err == nil && strings.EqualFold(u.Host, r.Host)
+}
+
+// SetModes sets the modes a ground station can switch between and the
+// function called on a mode change
+func (s *Server) SetModes(modes []string, current string, handler func(mode string) error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.modes = modes
+	s.mode = current
+	s.modeHandler = handler
+}
+
+// SetPose publishes the current drone pose
+func (s *Server) SetPose(pose localization.Pose) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.pose = &pose
+}
+
+// SetRings publishes the rings detected in the last frame
+func (s *Server) SetRings(rings map[int]*race.Ring) {
+	detections := make([]Detection, 0, len(rings))
+	for id, ring := range rings {
+		markers := 0
+		for _, m := range ring.Markers {
+			if m != nil {
+				markers++
+			}
+		}
+		detections = append(detections, Detection{ID: id, Position: ring.Position, Markers: markers})
+	}
+	sort.Slice(detections, func(i, j int) bool { return detections[i].ID < detections[j].ID })
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.rings = detections
+}
+
+// State returns a snapshot of the current state
+func (s *Server) State() State {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return State{
+		Time:       time.Now(),
+		Mode:       s.mode,
+		Modes:      s.modes,
+		FlightData: s.drone.FlightData(),
+		Velocity:   s.drone.GetVelocity(),
+		Pose:       s.pose,
+		Rings:      s.rings,
+	}
+}
+
+// Execute runs a ground station command
+func (s *Server) Execute(cmd Command) error {
+	s.mutex.Lock()
+	source := s.source
+	s.mutex.Unlock()
+
+	switch cmd.Command {
+	case "takeoff":
+		return s.drone.TakeOff()
+	case "land":
+		return s.drone.Land()
+	case "emergency":
+		return s.drone.Emergency()
+	case "hover":
+		cmd.Velocity = mgl32.Vec4{}
+		fallthrough
+	case "velocity":
+		if source != nil {
+			source.Set(cmd.Velocity)
+		} else {
+			drone.SetVelocity(s.drone, cmd.Velocity)
+		}
+		return nil
+	case "mode":
+		return s.setMode(cmd.Mode)
+	}
+	return fmt.Errorf("unknown command %q", cmd.Command)
+}
+
+func (s *Server) setMode(mode string) error {
+	s.mutex.Lock()
+	handler := s.modeHandler
+	known := false
+	for _, m := range s.modes {
+		if m == mode {
+			known = true
+		}
+	}
+	s.mutex.Unlock()
+
+	if !known || handler == nil {
+		return fmt.Errorf("unknown mode %q", mode)
+	}
+	if err := handler(mode); err != nil {
+		return err
+	}
+
+	s.mutex.Lock()
+	s.mode = mode
+	s.mutex.Unlock()
+	return nil
+}
+
+func (s *Server) handleState(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, s.State())
+}
+
+func (s *Server) handleTelemetry(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, s.drone.FlightData())
+}
+
+func (s *Server) handleRings(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, s.State().Rings)
+}
+
+func (s *Server) handleCommand(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "use POST", http.StatusMethodNotAllowed)
+		return
+	}
+	if err := s.authorized(r); err != nil {
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return
+	}
+	if !isJSON(r) {
+		http.Error(w, "use Content-Type application/json", http.StatusUnsupportedMediaType)
+		return
+	}
+	var cmd Command
+	if err := json.NewDecoder(r.Body).Decode(&cmd); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := s.Execute(cmd); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	writeJSON(w, s.State())
+}
+
+// handleWebSocket streams the state and accepts commands
+func (s *Server) handleWebSocket(w http.ResponseWriter, r *http.Request) {
+	if err := s.authorized(r); err != nil {
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return
+	}
+	conn, err := s.upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return
+	}
+
+	s.mutex.Lock()
+	s.clients[conn] = true
+	s.mutex.Unlock()
+
+	go func() {
+		defer s.removeClient(conn)
+		for {
+			var cmd Command
+			if err := conn.ReadJSON(&cmd); err != nil {
+				return
+			}
+			if err := s.Execute(cmd); err != nil {
+				log.Warn("command", logging.Fields{"command": cmd.Command, "error": err})
+			}
+		}
+	}()
+}
+
+func (s *Server) removeClient(conn *websocket.Conn) {
+	s.mutex.Lock()
+	delete(s.clients, conn)
+	s.mutex.Unlock()
+	conn.Close()
+}
+
+// broadcast sends the state to all websocket clients
+func (s *Server) broadcast() {
+	ticker := time.NewTicker(telemetryPeriod)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		state := s.State()
+
+		s.mutex.Lock()
+		clients := make([]*websocket.Conn, 0, len(s.clients))
+		for c := range s.clients {
+			clients = append(clients, c)
+		}
+		s.mutex.Unlock()
+
+		for _, c := range clients {
+			c.SetWriteDeadline(time.Now().Add(writeTimeout))
+			if err := c.WriteJSON(state); err != nil {
+				s.removeClient(c)
+			}
+		}
+	}
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(v)
+}
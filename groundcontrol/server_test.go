@@ -0,0 +1,218 @@
+package groundcontrol
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-gl/mathgl/mgl32"
+	"tellobot/drone"
+)
+
+// newTestServer returns a server with a token whose velocities go to the
+// returned source
+func newTestServer() (*Server, *drone.CommandMux, *drone.Source) {
+	d := drone.NewFake(drone.DefaultConfig())
+	s := NewServer(d)
+	s.SetToken("secret")
+	mux := drone.NewCommandMux(d)
+	source := mux.AddSource(drone.SourceConfig{Name: "ground", Priority: drone.PriorityRemote})
+	s.SetSource(source)
+	s.SetModes([]string{"manual", "track"}, "manual", func(mode string) error { return nil })
+	return s, mux, source
+}
+
+func TestCommand(t *testing.T) {
+	tests := []struct {
+		name    string
+		method  string
+		header  map[string]string
+		body    string
+		want    int
+		forward float32 // of the source afterwards
+	}{
+		{
+			"velocity",
+			"POST",
+			map[string]string{"Authorization": "Bearer secret", "Content-Type": "application/json"},
+			`{"command": "velocity", "velocity": [0, 0, 0.5, 0]}`,
+			http.StatusOK, 0.5,
+		},
+		{
+			"json with charset",
+			"POST",
+			map[string]string{"Authorization": "Bearer secret", "Content-Type": "application/json; charset=utf-8"},
+			`{"command": "velocity", "velocity": [0, 0, 0.5, 0]}`,
+			http.StatusOK, 0.5,
+		},
+		{
+			"same origin",
+			"POST",
+			map[string]string{"Authorization": "Bearer secret", "Content-Type": "application/json", "Origin": "http://ground"},
+			`{"command": "velocity", "velocity": [0, 0, 0.5, 0]}`,
+			http.StatusOK, 0.5,
+		},
+		{
+			"no token",
+			"POST",
+			map[string]string{"Content-Type": "application/json"},
+			`{"command": "velocity", "velocity": [0, 0, 0.5, 0]}`,
+			http.StatusForbidden, 0,
+		},
+		{
+			"wrong token",
+			"POST",
+			map[string]string{"Authorization": "Bearer guess", "Content-Type": "application/json"},
+			`{"command": "velocity", "velocity": [0, 0, 0.5, 0]}`,
+			http.StatusForbidden, 0,
+		},
+		{
+			"cross origin",
+			"POST",
+			map[string]string{"Authorization": "Bearer secret", "Content-Type": "application/json", "Origin": "http://evil"},
+			`{"command": "velocity", "velocity": [0, 0, 0.5, 0]}`,
+			http.StatusForbidden, 0,
+		},
+		{
+			"form",
+			"POST",
+			map[string]string{"Authorization": "Bearer secret", "Content-Type": "application/x-www-form-urlencoded"},
+			`{"command": "velocity", "velocity": [0, 0, 0.5, 0]}`,
+			http.StatusUnsupportedMediaType, 0,
+		},
+		{
+			"text",
+			"POST",
+			map[string]string{"Authorization": "Bearer secret", "Content-Type": "text/plain"},
+			`{"command": "velocity", "velocity": [0, 0, 0.5, 0]}`,
+			http.StatusUnsupportedMediaType, 0,
+		},
+		{
+			"get",
+			"GET",
+			map[string]string{"Authorization": "Bearer secret"},
+			"",
+			http.StatusMethodNotAllowed, 0,
+		},
+		{
+			"unknown command",
+			"POST",
+			map[string]string{"Authorization": "Bearer secret", "Content-Type": "application/json"},
+			`{"command": "barrel-roll"}`,
+			http.StatusBadRequest, 0,
+		},
+		{
+			"unknown mode",
+			"POST",
+			map[string]string{"Authorization": "Bearer secret", "Content-Type": "application/json"},
+			`{"command": "mode", "mode": "race"}`,
+			http.StatusBadRequest, 0,
+		},
+		{
+			"broken json",
+			"POST",
+			map[string]string{"Authorization": "Bearer secret", "Content-Type": "application/json"},
+			`{"command":`,
+			http.StatusBadRequest, 0,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			s, mux, source := newTestServer()
+			r := httptest.NewRequest(test.method, "http://ground/api/command", strings.NewReader(test.body))
+			for k, v := range test.header {
+				r.Header.Set(k, v)
+			}
+			w := httptest.NewRecorder()
+			s.mux.ServeHTTP(w, r)
+
+			if w.Code != test.want {
+				t.Fatalf("status %d, want %d: %s", w.Code, test.want, w.Body.String())
+			}
+			mux.Update()
+			if forward := mux.Output()[2]; forward != test.forward {
+				t.Errorf("forward %v, want %v", forward, test.forward)
+			}
+			if test.want != http.StatusOK && source.Active() {
+				t.Error("rejected command reached the source")
+			}
+		})
+	}
+}
+
+func TestCommandTokenQuery(t *testing.T) {
+	s, mux, _ := newTestServer()
+	body := `{"command": "velocity", "velocity": [0, 0.5, 0, 0]}`
+	r := httptest.NewRequest("POST", "http://ground/api/command?token=secret", strings.NewReader(body))
+	r.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	s.mux.ServeHTTP(w, r)
+	if w.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", w.Code, w.Body.String())
+	}
+	mux.Update()
+	if up := mux.Output()[1]; up != 0.5 {
+		t.Errorf("up %v, want 0.5", up)
+	}
+}
+
+func TestCommandMode(t *testing.T) {
+	s, _, _ := newTestServer()
+	if err := s.Execute(Command{Command: "mode", Mode: "track"}); err != nil {
+		t.Fatal(err)
+	}
+	if mode := s.State().Mode; mode != "track" {
+		t.Errorf("mode %q, want track", mode)
+	}
+	if err := s.Execute(Command{Command: "mode", Mode: "race"}); err == nil {
+		t.Error("unknown mode accepted")
+	}
+	if mode := s.State().Mode; mode != "track" {
+		t.Errorf("mode %q after an unknown mode, want track", mode)
+	}
+}
+
+func TestCommandHover(t *testing.T) {
+	s, mux, _ := newTestServer()
+	s.Execute(Command{Command: "velocity", Velocity: mgl32.Vec4{0.1, 0.2, 0.3, 0.4}})
+	s.Execute(Command{Command: "hover"})
+	mux.Update()
+	if v := mux.Output(); v != (mgl32.Vec4{}) {
+		t.Errorf("velocity %v after hover", v)
+	}
+}
+
+func TestGuard(t *testing.T) {
+	called := false
+	h := Guard("secret", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+	}))
+	tests := []struct {
+		method      string
+		token       string
+		contentType string
+		want        bool
+	}{
+		{"GET", "", "", true},
+		{"POST", "secret", "application/json", true},
+		{"PUT", "secret", "application/json", true},
+		{"POST", "", "application/json", false},
+		{"POST", "secret", "text/plain", false},
+		{"PUT", "secret", "", false},
+	}
+	for _, test := range tests {
+		called = false
+		r := httptest.NewRequest(test.method, "http://ground/tuning/params", strings.NewReader("{}"))
+		if test.token != "" {
+			r.Header.Set("Authorization", "Bearer "+test.token)
+		}
+		if test.contentType != "" {
+			r.Header.Set("Content-Type", test.contentType)
+		}
+		h.ServeHTTP(httptest.NewRecorder(), r)
+		if called != test.want {
+			t.Errorf("%s with token %q and %q: passed %v, want %v", test.method, test.token, test.contentType, called, test.want)
+		}
+	}
+}
@@ -0,0 +1,161 @@
+package localization
+
+import (
+	"math"
+	"time"
+
+	"github.com/go-gl/mathgl/mgl32"
+	"gobot.io/x/gobot"
+	"gocv.io/x/gocv"
+	"gocv.io/x/gocv/contrib"
+	"tellobot/drone"
+)
+
+// PoseEvent is published with a *Pose every time the drone pose is solved
+const PoseEvent = "pose"
+
+const (
+	// assumed corner detection noise in pixels
+	cornerNoise = 1.0
+	// reprojection error (px) above which a solution is rejected
+	maxReprojectionError = 8.0
+)
+
+// Pose is the drone pose in the world frame defined by the marker map
+type Pose struct {
+	Position    mgl32.Vec3 // drone position in world coordinates (m)
+	Rotation    mgl32.Mat3 // rotation from drone coordinates to world coordinates
+	Covariance  mgl32.Mat3 // position covariance in world coordinates (m^2)
+	YawVariance float32    // variance of the heading (rad^2)
+	Markers     []int      // ids of the markers used for the solution
+	Error       float32    // rms reprojection error (px)
+	Time        time.Time
+}
+
+// ToDrone transforms a point given in world coordinates to drone coordinates
+func (p *Pose) ToDrone(v mgl32.Vec3) mgl32.Vec3 {
+	return p.Rotation.Transpose().Mul3x1(v.Sub(p.Position))
+}
+
+// ToWorld transforms a point given in drone coordinates to world coordinates
+func (p *Pose) ToWorld(v mgl32.Vec3) mgl32.Vec3 {
+	return p.Position.Add(p.Rotation.Mul3x1(v))
+}
+
+// Localizer solves the drone pose from the markers visible in a frame
+type Localizer struct {
+	gobot.Eventer
+
+	dict      contrib.ArucoDictionary
+	markerMap *MarkerMap
+	pose      *Pose
+}
+
+func NewLocalizer(markerMap *MarkerMap) *Localizer {
+	l := &Localizer{
+		Eventer:   gobot.NewEventer(),
+		markerMap: markerMap,
+	}
+	l.dict = contrib.NewArucoPredefinedDictionary(contrib.ArucoPredefinedDict_5x5_50)
+	l.AddEvent(PoseEvent)
+
+	return l
+}
+
+func (l *Localizer) Close() {
+	l.dict.Close()
+}
+
+// Pose returns the last solved pose or nil if none has been solved yet
+func (l *Localizer) Pose() *Pose {
+	return l.pose
+}
+
+// Update detects the map markers in img and solves the drone pose from all
+// of them. The pose is published as PoseEvent and returned, ok is false when
+// no mapped marker is visible or the solution is rejected.
+func (l *Localizer) Update(img *gocv.Mat, d drone.Drone) (pose *Pose, ok bool) {
+	corners, ids := l.dict.DetectMarkers(img)
+
+	var objectPoints []mgl32.Vec3
+	var imagePoints []mgl32.Vec2
+	var used []int
+	for i, id := range ids {
+		marker, found := l.markerMap.Marker(id)
+		if !found {
+			continue
+		}
+		objectPoints = append(objectPoints, marker.Corners()...)
+		imagePoints = append(imagePoints, corners[i]...)
+		used = append(used, id)
+	}
+
+	if len(used) == 0 {
+		return nil, false
+	}
+
+	rvec, tvec := contrib.SolvePnP(objectPoints, imagePoints, d.CameraMatrix(), d.DistortionCoefficients())
+
+	// reprojection error tells how well the markers agree with each other
+	projected := contrib.ProjectPoints(objectPoints, rvec, tvec, d.CameraMatrix(), d.DistortionCoefficients())
+	sum := float32(0)
+	for i, p := range projected {
+		sum += p.Sub(imagePoints[i]).LenSqr()
+	}
+	rms := float32(math.Sqrt(float64(sum / float32(len(projected)))))
+	if rms > maxReprojectionError {
+		return nil, false
+	}
+
+	// world -> camera
+	rot := contrib.Rodrigues(rvec)
+	camToWorld := rot.Transpose()
+
+	pose = &Pose{
+		Position: camToWorld.Mul3x1(tvec).Mul(-1),
+		Rotation: camToWorld.Mul3(d.DroneToCameraMatrix()),
+		Markers:  used,
+		Error:    rms,
+		Time:     time.Now(),
+	}
+	pose.Covariance, pose.YawVariance = l.covariance(objectPoints, rot, tvec, rms, d)
+
+	l.pose = pose
+	l.Publish(PoseEvent, pose)
+
+	return pose, true
+}
+
+// covariance estimates the position and heading uncertainty of a solution
+// from the distance to the markers, their spread and the reprojection error
+func (l *Localizer) covariance(objectPoints []mgl32.Vec3, rot mgl32.Mat3, tvec mgl32.Vec3, rms float32, d drone.Drone) (mgl32.Mat3, float32) {
+	focal := float32(d.CameraMatrix().GetDoubleAt(0, 0))
+
+	// spread of the object points is the baseline of the measurement
+	var center mgl32.Vec3
+	for _, p := range objectPoints {
+		center = center.Add(p)
+	}
+	center = center.Mul(1 / float32(len(objectPoints)))
+	baseline := float32(0)
+	for _, p := range objectPoints {
+		if r := p.Sub(center).Len(); r > baseline {
+			baseline = r
+		}
+	}
+
+	distance := rot.Mul3x1(center).Add(tvec).Z()
+	pixel := (rms + cornerNoise) / focal / float32(math.Sqrt(float64(len(objectPoints))))
+
+	lateral := distance * pixel
+	axial := distance * distance * pixel / baseline
+
+	// uncertainty is given in camera coordinates, rotate it to the world
+	camCov := mgl32.Diag3(mgl32.Vec3{lateral * lateral, lateral * lateral, axial * axial})
+	camToWorld := rot.Transpose()
+	cov := camToWorld.Mul3(camCov).Mul3(rot)
+
+	yaw := lateral / baseline
+
+	return cov, yaw * yaw
+}
@@ -0,0 +1,97 @@
+package localization
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/go-gl/mathgl/mgl32"
+)
+
+// MapMarker is a single ArUco marker placed at a known pose in the world
+type MapMarker struct {
+	ID       int        `json:"id"`
+	Position mgl32.Vec3 `json:"position"` // marker center in world coordinates (m)
+	Rotation mgl32.Vec3 `json:"rotation"` // rotation around x, y and z axis (degrees), see Corners
+	Size     float32    `json:"size"`     // edge length (m), 0 uses the map default
+}
+
+// MarkerMap holds the world poses of all markers in the flying room. The world
+// frame follows the drone convention: x right, y down and z forward.
+type MarkerMap struct {
+	MarkerSize float32      `json:"markerSize"`
+	Markers    []*MapMarker `json:"markers"`
+
+	byID map[int]*MapMarker
+}
+
+// LoadMarkerMap reads a marker map from a json file
+func LoadMarkerMap(filename string) (*MarkerMap, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	m := &MarkerMap{}
+	if err := json.NewDecoder(f).Decode(m); err != nil {
+		return nil, fmt.Errorf("marker map %s: %v", filename, err)
+	}
+	if err := m.index(); err != nil {
+		return nil, fmt.Errorf("marker map %s: %v", filename, err)
+	}
+	return m, nil
+}
+
+func (m *MarkerMap) index() error {
+	m.byID = make(map[int]*MapMarker)
+	for _, marker := range m.Markers {
+		if _, ok := m.byID[marker.ID]; ok {
+			return fmt.Errorf("duplicate marker id %d", marker.ID)
+		}
+		if marker.Size == 0 {
+			marker.Size = m.MarkerSize
+		}
+		if marker.Size <= 0 {
+			return fmt.Errorf("marker %d has no size", marker.ID)
+		}
+		m.byID[marker.ID] = marker
+	}
+	return nil
+}
+
+// Marker returns the marker with the given id
+func (m *MarkerMap) Marker(id int) (*MapMarker, bool) {
+	marker, ok := m.byID[id]
+	return marker, ok
+}
+
+// Corners returns the marker corners in world coordinates in the same order
+// as the detector reports them: NW, NE, SE, SW. A marker with rotation 0
+// stands upright and faces -z, it is read by a drone looking along +z, so
+// its top edge lies at -y and its left edge at -x.
+func (m *MapMarker) Corners() []mgl32.Vec3 {
+	s := m.Size / 2
+	local := []mgl32.Vec3{
+		{-s, -s, 0},
+		{+s, -s, 0},
+		{+s, +s, 0},
+		{-s, +s, 0},
+	}
+
+	rot := RotationFromDegrees(m.Rotation)
+	corners := make([]mgl32.Vec3, len(local))
+	for i, c := range local {
+		corners[i] = m.Position.Add(rot.Mul3x1(c))
+	}
+	return corners
+}
+
+// RotationFromDegrees builds a rotation matrix from rotations around the x, y
+// and z axis, applied in that order
+func RotationFromDegrees(r mgl32.Vec3) mgl32.Mat3 {
+	x := mgl32.Rotate3DX(mgl32.DegToRad(r[0]))
+	y := mgl32.Rotate3DY(mgl32.DegToRad(r[1]))
+	z := mgl32.Rotate3DZ(mgl32.DegToRad(r[2]))
+	return z.Mul3(y).Mul3(x)
+}
@@ -0,0 +1,222 @@
+package localization
+
+import (
+	"math"
+	"sync"
+	"time"
+
+	"github.com/go-gl/mathgl/mgl32"
+	"gobot.io/x/gobot/platforms/dji/tello"
+	"gocv.io/x/gocv"
+	"tellobot/drone"
+)
+
+const (
+	// velocityNoise is the velocity uncertainty in m/s of the flight data
+	velocityNoise = 0.15
+	// yawRateNoise is the rotation speed uncertainty in rad/s
+	yawRateNoise = 0.2
+	// maxPredictStep limits how long a single prediction may integrate
+	maxPredictStep = 0.5
+	// initialVariance makes the first fix replace the initial pose
+	initialVariance = 1e4
+)
+
+// DriftStats describes how far the dead reckoned pose has drifted
+type DriftStats struct {
+	SinceFix         time.Duration // time since the last absolute fix
+	DistanceSinceFix float32       // distance flown since the last fix (m)
+	PositionStdDev   float32       // current position uncertainty (m)
+	YawStdDev        float32       // current heading uncertainty (rad)
+	Fixes            int           // number of absolute fixes applied
+	LastCorrection   float32       // position jump at the last fix (m)
+	MaxCorrection    float32       // largest position jump so far (m)
+	DriftRate        float32       // average correction per second of dead reckoning (m/s)
+}
+
+// Odometry dead reckons the drone pose between absolute fixes from flight
+// data velocities and, when frames are supplied, sparse optical flow.
+//
+// The Tello flight data carries no attitude, so the heading is integrated from
+// the commanded rotation and refined with the horizontal image flow.
+type Odometry struct {
+	mutex sync.Mutex
+
+	pose       Pose
+	stats      DriftStats
+	lastUpdate time.Time
+	lastFix    time.Time
+	driftTime  time.Duration
+	driftTotal float32
+
+	flow *opticalFlow
+
+	// yawRate is the rotation speed in rad/s at full clockwise power
+	yawRate float32
+}
+
+// NewOdometry creates an estimator starting at the world origin with an
+// unknown pose. yawRate is the rotation speed in rad/s at full clockwise
+// power, e.g. race.search.yawRate.
+func NewOdometry(yawRate float32) *Odometry {
+	o := &Odometry{yawRate: yawRate}
+	o.pose.Rotation = mgl32.Ident3()
+	o.pose.Covariance = mgl32.Diag3(mgl32.Vec3{initialVariance, initialVariance, initialVariance})
+	o.pose.YawVariance = initialVariance
+	return o
+}
+
+// EnableFlow turns on optical flow heading estimation for UpdateFlow
+func (o *Odometry) EnableFlow() {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	if o.flow == nil {
+		o.flow = newOpticalFlow()
+	}
+}
+
+func (o *Odometry) Close() {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	if o.flow != nil {
+		o.flow.Close()
+		o.flow = nil
+	}
+}
+
+// Predict advances the pose with the flight data velocities and the
+// currently commanded rotation
+func (o *Odometry) Predict(fd *tello.FlightData, d drone.Drone, now time.Time) {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+
+	if o.lastUpdate.IsZero() || fd == nil {
+		o.lastUpdate = now
+		return
+	}
+	dt := float32(now.Sub(o.lastUpdate).Seconds())
+	o.lastUpdate = now
+	if dt <= 0 {
+		return
+	}
+	if dt > maxPredictStep {
+		dt = maxPredictStep
+	}
+
+	// flight data speeds are in dm/s relative to the heading, y points down
+	velocity := mgl32.Vec3{
+		float32(fd.EastSpeed) / 10,
+		-float32(fd.VerticalSpeed) / 10,
+		float32(fd.NorthSpeed) / 10,
+	}
+
+	o.rotate(d.GetVelocity()[3] * o.yawRate * dt)
+
+	step := o.pose.Rotation.Mul3x1(velocity.Mul(dt))
+	o.pose.Position = o.pose.Position.Add(step)
+	o.pose.Time = now
+	o.stats.DistanceSinceFix += step.Len()
+
+	// uncertainty grows like a random walk
+	q := velocityNoise * velocityNoise * dt
+	o.pose.Covariance = o.pose.Covariance.Add(mgl32.Diag3(mgl32.Vec3{q, q, q}))
+	o.pose.YawVariance += yawRateNoise * yawRateNoise * dt
+}
+
+// UpdateFlow measures the heading change since the previous frame from the
+// horizontal optical flow and blends it with the predicted heading
+func (o *Odometry) UpdateFlow(img *gocv.Mat, d drone.Drone) {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+
+	if o.flow == nil {
+		return
+	}
+	dx, ok := o.flow.Update(img)
+	if !ok {
+		return
+	}
+
+	// scene moves left when the drone turns clockwise
+	focal := float32(d.CameraMatrix().GetDoubleAt(0, 0))
+	measured := -float32(math.Atan(float64(dx / focal)))
+
+	// the prediction already turned by the commanded rotation since the last
+	// frame, only correct the difference
+	predicted := o.flow.yaw
+	o.rotate((measured - predicted) * 0.5)
+	o.flow.yaw = 0
+}
+
+// Correct blends an absolute pose fix, e.g. from the Localizer, into the
+// estimate and updates the drift statistics
+func (o *Odometry) Correct(fix *Pose) {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+
+	// position: kalman update with full covariances
+	k := o.pose.Covariance.Mul3(o.pose.Covariance.Add(fix.Covariance).Inv())
+	correction := k.Mul3x1(fix.Position.Sub(o.pose.Position))
+	o.pose.Position = o.pose.Position.Add(correction)
+	o.pose.Covariance = mgl32.Ident3().Sub(k).Mul3(o.pose.Covariance)
+
+	// heading: scalar kalman update of the yaw around the drone y axis,
+	// pitch and roll are taken from the fix
+	gain := float32(1)
+	if o.pose.YawVariance+fix.YawVariance > 0 {
+		gain = o.pose.YawVariance / (o.pose.YawVariance + fix.YawVariance)
+	}
+	rel := o.pose.Rotation.Transpose().Mul3(fix.Rotation)
+	yawErr := float32(math.Atan2(float64(rel.At(0, 2)), float64(rel.At(2, 2))))
+	o.pose.Rotation = fix.Rotation.Mul3(mgl32.Rotate3DY(-(1 - gain) * yawErr))
+	o.pose.YawVariance = (1 - gain) * o.pose.YawVariance
+	o.pose.Markers = fix.Markers
+	o.pose.Time = fix.Time
+
+	// drift statistics
+	jump := correction.Len()
+	if !o.lastFix.IsZero() {
+		o.driftTime += fix.Time.Sub(o.lastFix)
+		o.driftTotal += jump
+		if o.driftTime > 0 {
+			o.stats.DriftRate = o.driftTotal / float32(o.driftTime.Seconds())
+		}
+	}
+	o.lastFix = fix.Time
+	o.stats.Fixes++
+	o.stats.LastCorrection = jump
+	if jump > o.stats.MaxCorrection {
+		o.stats.MaxCorrection = jump
+	}
+	o.stats.DistanceSinceFix = 0
+}
+
+// Pose returns the current pose estimate
+func (o *Odometry) Pose() Pose {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	return o.pose
+}
+
+// Drift returns the current drift statistics
+func (o *Odometry) Drift() DriftStats {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+
+	stats := o.stats
+	if !o.lastFix.IsZero() {
+		stats.SinceFix = time.Since(o.lastFix)
+	}
+	trace := o.pose.Covariance.At(0, 0) + o.pose.Covariance.At(1, 1) + o.pose.Covariance.At(2, 2)
+	stats.PositionStdDev = float32(math.Sqrt(float64(trace)))
+	stats.YawStdDev = float32(math.Sqrt(float64(o.pose.YawVariance)))
+	return stats
+}
+
+// rotate turns the pose clockwise around the drone y axis
+func (o *Odometry) rotate(angle float32) {
+	o.pose.Rotation = o.pose.Rotation.Mul3(mgl32.Rotate3DY(angle))
+	if o.flow != nil {
+		o.flow.yaw += angle
+	}
+}
@@ -0,0 +1,96 @@
+package localization
+
+import (
+	"sort"
+
+	"gocv.io/x/gocv"
+)
+
+const (
+	flowFeatures    = 100
+	flowQuality     = 0.01
+	flowMinDistance = 8
+	// features are searched again when fewer than this are left
+	flowMinFeatures = 20
+)
+
+// opticalFlow tracks sparse features between consecutive frames
+type opticalFlow struct {
+	prev     gocv.Mat
+	points   gocv.Mat
+	hasPrev  bool
+	features int
+
+	// heading change predicted since the previous frame (rad)
+	yaw float32
+}
+
+func newOpticalFlow() *opticalFlow {
+	return &opticalFlow{
+		prev:   gocv.NewMat(),
+		points: gocv.NewMat(),
+	}
+}
+
+func (f *opticalFlow) Close() {
+	f.prev.Close()
+	f.points.Close()
+}
+
+// Update returns the median horizontal feature motion in pixels since the
+// previous frame. ok is false if there was nothing to compare against.
+func (f *opticalFlow) Update(img *gocv.Mat) (dx float32, ok bool) {
+	gray := gocv.NewMat()
+	gocv.CvtColor(*img, &gray, gocv.ColorBGRToGray)
+
+	if f.hasPrev && f.features > 0 {
+		next := gocv.NewMat()
+		status := gocv.NewMat()
+		errs := gocv.NewMat()
+		gocv.CalcOpticalFlowPyrLK(f.prev, gray, f.points, next, &status, &errs)
+
+		var shifts []float32
+		var found []int
+		for i := 0; i < status.Rows(); i++ {
+			if status.GetUCharAt(i, 0) != 1 {
+				continue
+			}
+			shifts = append(shifts, next.GetFloatAt(i, 0)-f.points.GetFloatAt(i, 0))
+			found = append(found, i)
+		}
+		if len(shifts) > 0 {
+			sort.Slice(shifts, func(i, j int) bool { return shifts[i] < shifts[j] })
+			dx = shifts[len(shifts)/2]
+			ok = true
+		}
+
+		// lost features would be tracked on from where the search gave up
+		f.points.Close()
+		f.points = keep(next, found)
+		f.features = len(found)
+		next.Close()
+		status.Close()
+		errs.Close()
+	}
+
+	if f.features < flowMinFeatures {
+		gocv.GoodFeaturesToTrack(gray, &f.points, flowFeatures, flowQuality, flowMinDistance)
+		f.features = f.points.Rows()
+	}
+
+	f.prev.Close()
+	f.prev = gray
+	f.hasPrev = true
+
+	return dx, ok
+}
+
+// keep returns the points of the given rows
+func keep(points gocv.Mat, rows []int) gocv.Mat {
+	kept := gocv.NewMatWithSize(len(rows), 1, gocv.MatTypeCV32FC2)
+	for i, row := range rows {
+		kept.SetFloatAt(i, 0, points.GetFloatAt(row, 0))
+		kept.SetFloatAt(i, 1, points.GetFloatAt(row, 1))
+	}
+	return kept
+}
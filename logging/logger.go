@@ -0,0 +1,222 @@
+package logging
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+type Level int
+
+const (
+	Debug Level = iota
+	Info
+	Warn
+	Error
+)
+
+var levelNames = []string{"debug", "info", "warn", "error"}
+
+func (l Level) String() string {
+	if l < Debug || l > Error {
+		return fmt.Sprintf("level(%d)", int(l))
+	}
+	return levelNames[l]
+}
+
+func ParseLevel(name string) (Level, error) {
+	for i, n := range levelNames {
+		if strings.EqualFold(n, name) {
+			return Level(i), nil
+		}
+	}
+	return Info, fmt.Errorf("unknown log level %q", name)
+}
+
+// Fields are the inputs and outputs of a decision, e.g. the ring pose and the
+// chosen powers
+type Fields map[string]interface{}
+
+// Default is the root logger all packages log through, it writes text to
+// stdout until configured otherwise
+var Default = New(os.Stdout, Info, false, 0)
+
+// output is shared by a logger and all loggers derived from it
+type output struct {
+	mutex    sync.Mutex
+	w        io.Writer
+	level    Level
+	json     bool
+	interval time.Duration
+	last     map[string]time.Time
+	skipped  map[string]int
+}
+
+// Logger writes levelled messages with fields as text or JSON lines. Below
+// Warn, the same message of a component is written at most once per rate
+// limit interval; the number of dropped messages is added as the field
+// "suppressed".
+type Logger struct {
+	out       *output
+	component string
+}
+
+func New(w io.Writer, level Level, jsonLines bool, interval time.Duration) *Logger {
+	return &Logger{out: &output{
+		w:        w,
+		level:    level,
+		json:     jsonLines,
+		interval: interval,
+		last:     make(map[string]time.Time),
+		skipped:  make(map[string]int),
+	}}
+}
+
+// With returns a logger for a component sharing the output
+func (l *Logger) With(component string) *Logger {
+	return &Logger{out: l.out, component: component}
+}
+
+// Configure changes the output of the logger and all loggers derived from it
+func (l *Logger) Configure(w io.Writer, level Level, jsonLines bool, interval time.Duration) {
+	o := l.out
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	o.w = w
+	o.level = level
+	o.json = jsonLines
+	o.interval = interval
+}
+
+// Enabled returns true if messages of the level are written, so expensive
+// fields can be skipped
+func (l *Logger) Enabled(level Level) bool {
+	l.out.mutex.Lock()
+	defer l.out.mutex.Unlock()
+	return level >= l.out.level
+}
+
+func (l *Logger) Debug(msg string, fields Fields) { l.Log(Debug, msg, fields) }
+func (l *Logger) Info(msg string, fields Fields)  { l.Log(Info, msg, fields) }
+func (l *Logger) Warn(msg string, fields Fields)  { l.Log(Warn, msg, fields) }
+func (l *Logger) Error(msg string, fields Fields) { l.Log(Error, msg, fields) }
+
+func (l *Logger) Log(level Level, msg string, fields Fields) {
+	o := l.out
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+
+	if level < o.level {
+		return
+	}
+
+	now := time.Now()
+	key := l.component + "\x00" + msg
+	suppressed := 0
+	if level < Warn && o.interval > 0 {
+		if now.Sub(o.last[key]) < o.interval {
+			o.skipped[key]++
+			return
+		}
+		o.last[key] = now
+		suppressed = o.skipped[key]
+		delete(o.skipped, key)
+	}
+
+	if o.json {
+		entry := make(map[string]interface{}, len(fields)+5)
+		for k, v := range fields {
+			entry[k] = v
+		}
+		entry["time"] = now.Format(time.RFC3339Nano)
+		entry["level"] = level.String()
+		entry["msg"] = msg
+		if l.component != "" {
+			entry["component"] = l.component
+		}
+		if suppressed > 0 {
+			entry["suppressed"] = suppressed
+		}
+		buf, err := json.Marshal(entry)
+		if err != nil {
+			buf, _ = json.Marshal(map[string]interface{}{"time": entry["time"], "level": "error", "msg": "log: " + err.Error()})
+		}
+		o.w.Write(append(buf, '\n'))
+		return
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s %-5s", now.Format("15:04:05.000"), level)
+	if l.component != "" {
+		fmt.Fprintf(&b, " %s:", l.component)
+	}
+	b.WriteString(" " + msg)
+	keys := make([]string, 0, len(fields))
+	for k := range fields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		fmt.Fprintf(&b, " %s=%v", k, fields[k])
+	}
+	if suppressed > 0 {
+		fmt.Fprintf(&b, " suppressed=%d", suppressed)
+	}
+	b.WriteString("\n")
+	io.WriteString(o.w, b.String())
+}
+
+// Config selects where and how much is logged
+type Config struct {
+	Level     string        `yaml:"level"`     // debug, info, warn or error
+	Format    string        `yaml:"format"`    // text or json
+	File      string        `yaml:"file"`      // empty logs to stdout
+	RateLimit time.Duration `yaml:"rateLimit"` // per message, below warn
+}
+
+func DefaultConfig() Config {
+	return Config{
+		Level:     "info",
+		Format:    "text",
+		RateLimit: 100 * time.Millisecond,
+	}
+}
+
+func (c Config) Validate() error {
+	if _, err := ParseLevel(c.Level); err != nil {
+		return err
+	}
+	if c.Format != "text" && c.Format != "json" {
+		return fmt.Errorf("unknown log format %q, use text or json", c.Format)
+	}
+	if c.RateLimit < 0 {
+		return fmt.Errorf("negative log rate limit %v", c.RateLimit)
+	}
+	return nil
+}
+
+// Open configures the default logger. A log file is appended to, it has to be
+// closed with the returned function.
+func Open(c Config) (close func() error, err error) {
+	level, err := ParseLevel(c.Level)
+	if err != nil {
+		return nil, err
+	}
+	var w io.Writer = os.Stdout
+	close = func() error { return nil }
+	if c.File != "" {
+		f, err := os.OpenFile(c.File, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+		if err != nil {
+			return nil, fmt.Errorf("log %s: %v", c.File, err)
+		}
+		w = f
+		close = f.Close
+	}
+	Default.Configure(w, level, c.Format == "json", c.RateLimit)
+	return close, nil
+}
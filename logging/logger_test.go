@@ -0,0 +1,132 @@
+package logging
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+// lines returns the json lines written to buf
+func lines(t *testing.T, buf *bytes.Buffer) []map[string]interface{} {
+	var entries []map[string]interface{}
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		if line == "" {
+			continue
+		}
+		entry := make(map[string]interface{})
+		if err := json.Unmarshal([]byte(line), &entry); err != nil {
+			t.Fatalf("line %q: %v", line, err)
+		}
+		entries = append(entries, entry)
+	}
+	return entries
+}
+
+func TestJSONLine(t *testing.T) {
+	var buf bytes.Buffer
+	l := New(&buf, Debug, true, 0).With("race")
+	l.Info("gate passed", Fields{"gate": 2, "speed": 0.5})
+
+	entries := lines(t, &buf)
+	if len(entries) != 1 {
+		t.Fatalf("%d lines, want 1", len(entries))
+	}
+	e := entries[0]
+	want := map[string]interface{}{
+		"level":     "info",
+		"msg":       "gate passed",
+		"component": "race",
+		"gate":      2.0,
+		"speed":     0.5,
+	}
+	for k, v := range want {
+		if e[k] != v {
+			t.Errorf("%s is %v, want %v", k, e[k], v)
+		}
+	}
+	if _, err := time.Parse(time.RFC3339Nano, e["time"].(string)); err != nil {
+		t.Errorf("time: %v", err)
+	}
+	if len(e) != len(want)+1 {
+		t.Errorf("unexpected fields in %v", e)
+	}
+}
+
+func TestLevel(t *testing.T) {
+	var buf bytes.Buffer
+	l := New(&buf, Warn, true, 0)
+	l.Debug("debug", nil)
+	l.Info("info", nil)
+	l.Warn("warn", nil)
+	l.Error("error", nil)
+
+	entries := lines(t, &buf)
+	if len(entries) != 2 || entries[0]["msg"] != "warn" || entries[1]["msg"] != "error" {
+		t.Errorf("got %v, want warn and error", entries)
+	}
+	if l.Enabled(Info) || !l.Enabled(Error) {
+		t.Error("wrong levels enabled")
+	}
+}
+
+func TestRateLimit(t *testing.T) {
+	var buf bytes.Buffer
+	root := New(&buf, Debug, true, 50*time.Millisecond)
+	l := root.With("tracking")
+
+	for i := 0; i < 5; i++ {
+		l.Info("update", nil)
+	}
+	// other messages, components and warnings are limited on their own
+	l.Info("lock", nil)
+	root.With("race").Info("update", nil)
+	l.Warn("update", nil)
+	l.Warn("update", nil)
+
+	time.Sleep(60 * time.Millisecond)
+	l.Info("update", nil)
+
+	entries := lines(t, &buf)
+	if len(entries) != 6 {
+		t.Fatalf("%d lines, want 6: %v", len(entries), entries)
+	}
+	if _, ok := entries[0]["suppressed"]; ok {
+		t.Errorf("first line %v has a suppressed count", entries[0])
+	}
+	last := entries[5]
+	if last["msg"] != "update" || last["component"] != "tracking" || last["suppressed"] != 4.0 {
+		t.Errorf("last line %v, want 4 suppressed", last)
+	}
+}
+
+func TestTextLine(t *testing.T) {
+	var buf bytes.Buffer
+	l := New(&buf, Info, false, time.Hour).With("drone")
+	l.Info("take off", Fields{"height": 1.5, "battery": 80})
+	l.Info("take off", nil)
+
+	line := buf.String()
+	if strings.Count(line, "\n") != 1 {
+		t.Fatalf("got %q, want one line", line)
+	}
+	if !strings.HasSuffix(line, " info  drone: take off battery=80 height=1.5\n") {
+		t.Errorf("got %q", line)
+	}
+}
+
+func TestConfigValidate(t *testing.T) {
+	for _, c := range []Config{
+		{Level: "loud", Format: "text"},
+		{Level: "info", Format: "xml"},
+		{Level: "info", Format: "json", RateLimit: -time.Second},
+	} {
+		if err := c.Validate(); err == nil {
+			t.Errorf("%+v is valid", c)
+		}
+	}
+	if err := DefaultConfig().Validate(); err != nil {
+		t.Error(err)
+	}
+}
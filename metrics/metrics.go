@@ -0,0 +1,223 @@
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Default is the registry the packages register their metrics into
+var Default = NewRegistry()
+
+// DefaultBuckets are histogram buckets in seconds for durations of a frame
+var DefaultBuckets = []float64{0.001, 0.0025, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1}
+
+// metric is anything that can write itself in the prometheus text format
+type metric interface {
+	name() string
+	write(w io.Writer)
+}
+
+// Registry holds metrics and serves them
+type Registry struct {
+	mutex   sync.Mutex
+	metrics map[string]metric
+}
+
+func NewRegistry() *Registry {
+	return &Registry{metrics: make(map[string]metric)}
+}
+
+func (r *Registry) register(m metric) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if _, ok := r.metrics[m.name()]; ok {
+		panic("metrics: duplicate metric " + m.name())
+	}
+	r.metrics[m.name()] = m
+}
+
+// Write writes all metrics sorted by name in the prometheus text format
+func (r *Registry) Write(w io.Writer) {
+	r.mutex.Lock()
+	metrics := make([]metric, 0, len(r.metrics))
+	for _, m := range r.metrics {
+		metrics = append(metrics, m)
+	}
+	r.mutex.Unlock()
+
+	sort.Slice(metrics, func(i, j int) bool { return metrics[i].name() < metrics[j].name() })
+	for _, m := range metrics {
+		m.write(w)
+	}
+}
+
+// Handler serves the metrics, e.g. on /metrics
+func (r *Registry) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+		r.Write(w)
+	})
+}
+
+type desc struct {
+	metricName string
+	help       string
+	kind       string
+}
+
+func (d desc) name() string {
+	return d.metricName
+}
+
+func (d desc) header(w io.Writer) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", d.metricName, d.help, d.metricName, d.kind)
+}
+
+// Counter only goes up
+type Counter struct {
+	desc
+	mutex  sync.Mutex
+	values map[string]float64 // by label value
+	label  string
+}
+
+// NewCounter registers a counter, label may be empty. A counter with a
+// label is counted per label value, e.g. per axis.
+func (r *Registry) NewCounter(name string, help string, label string) *Counter {
+	c := &Counter{desc: desc{name, help, "counter"}, values: make(map[string]float64), label: label}
+	r.register(c)
+	return c
+}
+
+func (c *Counter) Inc() {
+	c.Add("", 1)
+}
+
+// IncLabel counts one for a label value
+func (c *Counter) IncLabel(value string) {
+	c.Add(value, 1)
+}
+
+func (c *Counter) Add(labelValue string, v float64) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.values[labelValue] += v
+}
+
+func (c *Counter) write(w io.Writer) {
+	c.header(w)
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if len(c.values) == 0 && c.label == "" {
+		fmt.Fprintf(w, "%s 0\n", c.metricName)
+	}
+	keys := make([]string, 0, len(c.values))
+	for k := range c.values {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		fmt.Fprintf(w, "%s%s %s\n", c.metricName, labels(c.label, k), format(c.values[k]))
+	}
+}
+
+// Gauge is a value that goes up and down
+type Gauge struct {
+	desc
+	mutex sync.Mutex
+	value float64
+}
+
+func (r *Registry) NewGauge(name string, help string) *Gauge {
+	g := &Gauge{desc: desc{name, help, "gauge"}}
+	r.register(g)
+	return g
+}
+
+func (g *Gauge) Set(v float64) {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+	g.value = v
+}
+
+func (g *Gauge) write(w io.Writer) {
+	g.header(w)
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+	fmt.Fprintf(w, "%s %s\n", g.metricName, format(g.value))
+}
+
+// Histogram counts observations into buckets
+type Histogram struct {
+	desc
+	mutex   sync.Mutex
+	buckets []float64
+	counts  []uint64
+	count   uint64
+	sum     float64
+}
+
+func (r *Registry) NewHistogram(name string, help string, buckets []float64) *Histogram {
+	h := &Histogram{
+		desc:    desc{name, help, "histogram"},
+		buckets: buckets,
+		counts:  make([]uint64, len(buckets)),
+	}
+	r.register(h)
+	return h
+}
+
+func (h *Histogram) Observe(v float64) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	for i, b := range h.buckets {
+		if v <= b {
+			h.counts[i]++
+		}
+	}
+	h.count++
+	h.sum += v
+}
+
+// ObserveSince observes the seconds since start
+func (h *Histogram) ObserveSince(start time.Time) {
+	h.Observe(time.Since(start).Seconds())
+}
+
+func (h *Histogram) write(w io.Writer) {
+	h.header(w)
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	for i, b := range h.buckets {
+		fmt.Fprintf(w, "%s_bucket%s %d\n", h.metricName, labels("le", format(b)), h.counts[i])
+	}
+	fmt.Fprintf(w, "%s_bucket%s %d\n", h.metricName, labels("le", "+Inf"), h.count)
+	fmt.Fprintf(w, "%s_sum %s\n", h.metricName, format(h.sum))
+	fmt.Fprintf(w, "%s_count %d\n", h.metricName, h.count)
+}
+
+func labels(name string, value string) string {
+	if name == "" {
+		return ""
+	}
+	value = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(value)
+	return fmt.Sprintf("{%s=\"%s\"}", name, value)
+}
+
+func format(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case math.IsNaN(v):
+		return "NaN"
+	}
+	return fmt.Sprintf("%g", v)
+}
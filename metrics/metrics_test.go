@@ -0,0 +1,124 @@
+package metrics
+
+import (
+	"bytes"
+	"math"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func output(r *Registry) string {
+	var buf bytes.Buffer
+	r.Write(&buf)
+	return buf.String()
+}
+
+func TestHistogram(t *testing.T) {
+	r := NewRegistry()
+	h := r.NewHistogram("frame_seconds", "Time per frame.", []float64{0.01, 0.1, 1})
+	for _, v := range []float64{0.005, 0.01, 0.05, 0.5, 2} {
+		h.Observe(v)
+	}
+
+	// buckets are cumulative, +Inf holds everything like the count
+	want := `# HELP frame_seconds Time per frame.
+# TYPE frame_seconds histogram
+frame_seconds_bucket{le="0.01"} 2
+frame_seconds_bucket{le="0.1"} 3
+frame_seconds_bucket{le="1"} 4
+frame_seconds_bucket{le="+Inf"} 5
+frame_seconds_sum 2.565
+frame_seconds_count 5
+`
+	if got := output(r); got != want {
+		t.Errorf("got\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestEmptyHistogram(t *testing.T) {
+	r := NewRegistry()
+	r.NewHistogram("frame_seconds", "Time per frame.", []float64{1})
+	got := output(r)
+	for _, line := range []string{`frame_seconds_bucket{le="1"} 0`, `frame_seconds_bucket{le="+Inf"} 0`, "frame_seconds_sum 0", "frame_seconds_count 0"} {
+		if !strings.Contains(got, line+"\n") {
+			t.Errorf("no %q in\n%s", line, got)
+		}
+	}
+}
+
+func TestCounter(t *testing.T) {
+	r := NewRegistry()
+	r.NewCounter("b_total", "Plain.", "")
+	c := r.NewCounter("a_total", "Per axis.", "axis")
+	c.IncLabel("yaw")
+	c.IncLabel("yaw")
+	c.Add("up", 0.5)
+
+	// sorted by name and label value, a plain counter starts at 0
+	want := `# HELP a_total Per axis.
+# TYPE a_total counter
+a_total{axis="up"} 0.5
+a_total{axis="yaw"} 2
+# HELP b_total Plain.
+# TYPE b_total counter
+b_total 0
+`
+	if got := output(r); got != want {
+		t.Errorf("got\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestLabelEscaping(t *testing.T) {
+	r := NewRegistry()
+	c := r.NewCounter("errors_total", "Errors.", "error")
+	c.IncLabel(`read "frame"` + "\n" + `C:\video`)
+
+	want := `errors_total{error="read \"frame\"\nC:\\video"} 1` + "\n"
+	if got := output(r); !strings.HasSuffix(got, want) {
+		t.Errorf("got\n%s\nwant the line\n%s", got, want)
+	}
+}
+
+func TestGauge(t *testing.T) {
+	r := NewRegistry()
+	g := r.NewGauge("battery", "Battery.")
+	for _, test := range []struct {
+		v    float64
+		want string
+	}{
+		{80, "battery 80\n"},
+		{0.25, "battery 0.25\n"},
+		{math.Inf(1), "battery +Inf\n"},
+		{math.NaN(), "battery NaN\n"},
+	} {
+		g.Set(test.v)
+		if got := output(r); !strings.HasSuffix(got, test.want) {
+			t.Errorf("got %q, want %q", got, test.want)
+		}
+	}
+}
+
+func TestDuplicate(t *testing.T) {
+	r := NewRegistry()
+	r.NewGauge("battery", "Battery.")
+	defer func() {
+		if recover() == nil {
+			t.Error("registered twice")
+		}
+	}()
+	r.NewCounter("battery", "Battery.", "")
+}
+
+func TestHandler(t *testing.T) {
+	r := NewRegistry()
+	r.NewGauge("battery", "Battery.").Set(80)
+	w := httptest.NewRecorder()
+	r.Handler().ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+	if ct := w.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/plain") {
+		t.Errorf("content type %q", ct)
+	}
+	if !strings.Contains(w.Body.String(), "battery 80\n") {
+		t.Errorf("got %q", w.Body.String())
+	}
+}
@@ -0,0 +1,376 @@
+package mission
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/go-gl/mathgl/mgl32"
+	"gobot.io/x/gobot"
+	"tellobot/drone"
+	"tellobot/localization"
+	"tellobot/logging"
+	"tellobot/race"
+)
+
+var log = logging.Default.With("mission")
+
+// ProgressEvent is published with a Progress whenever a step starts or the
+// state of the executor changes
+const ProgressEvent = "progress"
+
+const (
+	controlPeriod = 50 * time.Millisecond
+
+	takeOffTime    = 5 * time.Second
+	landTime       = 3 * time.Second
+	defaultTimeout = 30 * time.Second
+
+	defaultDistanceTolerance = float32(0.2) // m
+	defaultYawTolerance      = float32(5)   // degrees
+
+	// proportional gains from error to velocity (-1.0 to 1.0)
+	positionGain = float32(0.5) // per m
+	yawGain      = float32(1.0) // per rad
+	maxVelocity  = float32(0.4)
+
+	// gates are approached from this far in front and left this far behind
+	gateApproach = float32(1.0)
+	gateExit     = float32(0.5)
+)
+
+var ErrAborted = errors.New("mission aborted")
+
+type State int
+
+const (
+	Idle State = iota
+	Running
+	Paused
+	Done
+	Aborted
+	Failed
+)
+
+func (s State) String() string {
+	return [...]string{"idle", "running", "paused", "done", "aborted", "failed"}[s]
+}
+
+// Progress tells which step the executor is at
+type Progress struct {
+	Step  int // 1 based, 0 before the mission has started
+	Total int
+	Type  StepType
+	State State
+	Err   error
+}
+
+// Executor flies a mission on any drone. The pose comes from the odometry,
+// which the executor advances itself; callers may still feed it frames and
+// absolute fixes.
+type Executor struct {
+	gobot.Eventer
+
+	drone    drone.Drone
+	mission  *Mission
+	odometry *localization.Odometry
+	course   *race.Course
+
+	// how long take off and landing take, tests shorten them
+	takeOffTime time.Duration
+	landTime    time.Duration
+
+	mutex    sync.Mutex
+	state    State
+	progress Progress
+}
+
+func NewExecutor(d drone.Drone, m *Mission, odometry *localization.Odometry) *Executor {
+	e := &Executor{
+		Eventer:  gobot.NewEventer(),
+		drone:    d,
+		mission:  m,
+		odometry: odometry,
+
+		takeOffTime: takeOffTime,
+		landTime:    landTime,
+	}
+	e.progress.Total = len(m.Steps)
+	e.AddEvent(ProgressEvent)
+	return e
+}
+
+// SetCourse sets the course used to look up gates for gate steps
+func (e *Executor) SetCourse(c *race.Course) {
+	e.course = c
+}
+
+// Pause hovers the drone and stops the mission clock until Resume is called
+func (e *Executor) Pause() {
+	e.setState(Running, Paused)
+}
+
+// Resume continues a paused mission
+func (e *Executor) Resume() {
+	e.setState(Paused, Running)
+}
+
+// TogglePause pauses a running mission or resumes a paused one
+func (e *Executor) TogglePause() {
+	if e.State() == Paused {
+		e.Resume()
+	} else {
+		e.Pause()
+	}
+}
+
+// Abort stops the mission and lands the drone
+func (e *Executor) Abort() {
+	e.setState(Running, Aborted)
+	e.setState(Paused, Aborted)
+}
+
+func (e *Executor) State() State {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	return e.state
+}
+
+func (e *Executor) Progress() Progress {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	return e.progress
+}
+
+// setState changes the state if it currently is from
+func (e *Executor) setState(from State, to State) {
+	e.mutex.Lock()
+	if e.state != from {
+		e.mutex.Unlock()
+		return
+	}
+	e.state = to
+	e.progress.State = to
+	progress := e.progress
+	e.mutex.Unlock()
+
+	log.Info("state", logging.Fields{"state": to})
+	e.Publish(ProgressEvent, progress)
+}
+
+func (e *Executor) finish(state State, err error) error {
+	e.mutex.Lock()
+	e.state = state
+	e.progress.State = state
+	e.progress.Err = err
+	progress := e.progress
+	e.mutex.Unlock()
+
+	if err != nil {
+		log.Warn("state", logging.Fields{"state": state, "error": err})
+	} else {
+		log.Info("state", logging.Fields{"state": state})
+	}
+	e.Publish(ProgressEvent, progress)
+	return err
+}
+
+// stepState holds what a step needs between control ticks
+type stepState struct {
+	elapsed time.Duration
+	target  mgl32.Vec3
+	exit    mgl32.Vec3
+	yaw     float32 // target heading (rad)
+	phase   int
+}
+
+// Run flies the mission and blocks until it is done, aborted or failed
+func (e *Executor) Run() error {
+	e.setState(Idle, Running)
+
+	ticker := time.NewTicker(controlPeriod)
+	defer ticker.Stop()
+
+	for i, step := range e.mission.Steps {
+		e.mutex.Lock()
+		e.progress.Step = i + 1
+		e.progress.Type = step.Type
+		progress := e.progress
+		e.mutex.Unlock()
+
+		log.Info("step", logging.Fields{"step": i + 1, "steps": len(e.mission.Steps), "type": step.String()})
+		e.Publish(ProgressEvent, progress)
+
+		st, err := e.begin(step)
+		if err != nil {
+			e.hover()
+			return e.finish(Failed, fmt.Errorf("step %d (%s): %v", i+1, step, err))
+		}
+
+		timeout := defaultTimeout
+		if step.Timeout > 0 {
+			timeout = time.Duration(step.Timeout * float32(time.Second))
+		}
+
+		paused := false
+		last := time.Now()
+		for done := false; !done; {
+			now := <-ticker.C
+			dt := now.Sub(last)
+			last = now
+			e.odometry.Predict(e.drone.FlightData(), e.drone, now)
+
+			switch e.State() {
+			case Aborted:
+				e.hover()
+				e.drone.Land()
+				return e.finish(Aborted, ErrAborted)
+			case Paused:
+				if !paused {
+					e.hover()
+					paused = true
+				}
+				continue
+			}
+			paused = false
+
+			st.elapsed += dt
+			if st.elapsed > timeout && step.Type != StepHover {
+				e.hover()
+				return e.finish(Failed, fmt.Errorf("step %d (%s): timed out", i+1, step))
+			}
+			done = e.tick(step, st)
+		}
+	}
+
+	return e.finish(Done, nil)
+}
+
+// begin issues the initial command of a step and prepares its targets
+func (e *Executor) begin(step *Step) (*stepState, error) {
+	st := &stepState{}
+	pose := e.odometry.Pose()
+
+	switch step.Type {
+	case StepTakeOff:
+		return st, e.drone.TakeOff()
+	case StepLand:
+		e.hover()
+		return st, e.drone.Land()
+	case StepHover:
+		e.hover()
+	case StepGoTo:
+		st.target = step.Position
+		if step.Relative {
+			st.target = pose.ToWorld(step.Position)
+		}
+		st.yaw = heading(&pose)
+	case StepYaw:
+		st.yaw = mgl32.DegToRad(step.Yaw)
+		if step.Relative {
+			st.yaw += heading(&pose)
+		}
+	case StepGate:
+		if e.course == nil {
+			return nil, errors.New("no course")
+		}
+		gate, ok := e.course.Gate(step.Gate)
+		if !ok {
+			return nil, fmt.Errorf("no gate %d in course", step.Gate)
+		}
+		normal := gate.Normal()
+		st.target = gate.Position.Sub(normal.Mul(gateApproach))
+		st.exit = gate.Position.Add(normal.Mul(gateExit))
+		st.yaw = float32(math.Atan2(float64(normal[0]), float64(normal[2])))
+	}
+	return st, nil
+}
+
+// tick runs one control step and returns true when the step is complete
+func (e *Executor) tick(step *Step, st *stepState) bool {
+	pose := e.odometry.Pose()
+
+	distTol := defaultDistanceTolerance
+	yawTol := mgl32.DegToRad(defaultYawTolerance)
+	if step.Tolerance > 0 {
+		distTol = step.Tolerance
+		yawTol = mgl32.DegToRad(step.Tolerance)
+	}
+
+	switch step.Type {
+	case StepTakeOff:
+		return st.elapsed >= e.takeOffTime
+	case StepLand:
+		return st.elapsed >= e.landTime
+	case StepHover:
+		return st.elapsed >= time.Duration(step.Duration*float32(time.Second))
+	case StepYaw:
+		if e.flyTo(&pose, pose.Position, st.yaw) < yawTol {
+			e.hover()
+			return true
+		}
+	case StepGoTo:
+		e.flyTo(&pose, st.target, st.yaw)
+		if pose.Position.Sub(st.target).Len() < distTol {
+			e.hover()
+			return true
+		}
+	case StepGate:
+		e.flyTo(&pose, st.target, st.yaw)
+		if pose.Position.Sub(st.target).Len() < distTol {
+			if st.phase == 1 {
+				e.hover()
+				return true
+			}
+			// lined up in front of the gate, now fly through
+			st.phase = 1
+			st.target = st.exit
+		}
+	}
+	return false
+}
+
+// flyTo steers towards a world position and heading and returns the
+// remaining heading error (rad)
+func (e *Executor) flyTo(pose *localization.Pose, target mgl32.Vec3, yaw float32) float32 {
+	rel := pose.ToDrone(target)
+	yawErr := wrapAngle(yaw - heading(pose))
+
+	capVelocity := func(v float32) float32 {
+		return mgl32.Clamp(v, -maxVelocity, maxVelocity)
+	}
+
+	// drone coordinates have y pointing down, velocities up
+	drone.SetVelocity(e.drone, mgl32.Vec4{
+		capVelocity(rel[0] * positionGain),
+		capVelocity(-rel[1] * positionGain),
+		capVelocity(rel[2] * positionGain),
+		capVelocity(yawErr * yawGain),
+	})
+
+	return float32(math.Abs(float64(yawErr)))
+}
+
+func (e *Executor) hover() {
+	e.drone.Hover()
+	e.drone.CeaseRotation()
+}
+
+// heading returns the clockwise angle of the drone forward axis from the
+// world z axis
+func heading(pose *localization.Pose) float32 {
+	f := pose.Rotation.Mul3x1(mgl32.Vec3{0, 0, 1})
+	return float32(math.Atan2(float64(f[0]), float64(f[2])))
+}
+
+func wrapAngle(a float32) float32 {
+	for a > math.Pi {
+		a -= 2 * math.Pi
+	}
+	for a < -math.Pi {
+		a += 2 * math.Pi
+	}
+	return a
+}
@@ -0,0 +1,209 @@
+package mission
+
+import (
+	"math"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-gl/mathgl/mgl32"
+	"tellobot/drone"
+	"tellobot/localization"
+	"tellobot/race"
+)
+
+// simulate returns an executor flying the steps on the fake drone with short
+// take off and landing times
+func simulate(steps ...*Step) (*Executor, drone.Drone, *localization.Odometry) {
+	d := drone.NewFake(drone.DefaultConfig())
+	odometry := localization.NewOdometry(race.DefaultSearchConfig().YawRate)
+	e := NewExecutor(d, &Mission{Name: "test", Steps: steps}, odometry)
+	e.takeOffTime = 100 * time.Millisecond
+	e.landTime = 100 * time.Millisecond
+	return e, d, odometry
+}
+
+// run runs the executor and fails the test if it does not finish in time
+func run(t *testing.T, e *Executor, timeout time.Duration) error {
+	done := make(chan error, 1)
+	go func() {
+		done <- e.Run()
+	}()
+	select {
+	case err := <-done:
+		return err
+	case <-time.After(timeout):
+		e.Abort()
+		t.Fatalf("mission still at step %+v after %v", e.Progress(), timeout)
+		return nil
+	}
+}
+
+func flying(d drone.Drone) bool {
+	return d.FlightData().EmSky
+}
+
+func TestExecutorTakeOffHoverLand(t *testing.T) {
+	e, d, _ := simulate(
+		&Step{Type: StepTakeOff},
+		&Step{Type: StepHover, Duration: 0.2},
+		&Step{Type: StepLand},
+	)
+	var steps []int
+	e.On(ProgressEvent, func(data interface{}) {
+		steps = append(steps, data.(Progress).Step)
+	})
+
+	if err := run(t, e, 5*time.Second); err != nil {
+		t.Fatal(err)
+	}
+	if e.State() != Done || flying(d) {
+		t.Errorf("state %v, flying %v after the mission", e.State(), flying(d))
+	}
+	if p := e.Progress(); p.Step != 3 || p.Total != 3 {
+		t.Errorf("progress %+v", p)
+	}
+	if len(steps) == 0 {
+		t.Error("no progress published")
+	}
+}
+
+func TestExecutorGoTo(t *testing.T) {
+	e, d, odometry := simulate(
+		&Step{Type: StepTakeOff},
+		&Step{Type: StepGoTo, Position: mgl32.Vec3{0, 0, 0.6}, Relative: true},
+	)
+	if err := run(t, e, 10*time.Second); err != nil {
+		t.Fatal(err)
+	}
+	pos := odometry.Pose().Position
+	if pos.Sub(mgl32.Vec3{0, 0, 0.6}).Len() > defaultDistanceTolerance+0.05 {
+		t.Errorf("ended at %v", pos)
+	}
+	if v := d.GetVelocity(); v != (mgl32.Vec4{}) {
+		t.Errorf("still moving with %v", v)
+	}
+}
+
+func TestExecutorYaw(t *testing.T) {
+	e, _, odometry := simulate(
+		&Step{Type: StepTakeOff},
+		&Step{Type: StepYaw, Yaw: 45, Relative: true},
+	)
+	if err := run(t, e, 10*time.Second); err != nil {
+		t.Fatal(err)
+	}
+	pose := odometry.Pose()
+	yaw := mgl32.RadToDeg(heading(&pose))
+	if math.Abs(float64(yaw-45)) > float64(2*defaultYawTolerance) {
+		t.Errorf("heading %v°, want 45°", yaw)
+	}
+}
+
+func TestExecutorGate(t *testing.T) {
+	e, _, odometry := simulate(
+		&Step{Type: StepTakeOff},
+		&Step{Type: StepGate, Gate: 1, Tolerance: 0.5},
+	)
+	// lined up in front of the gate from the start, so it only flies through
+	e.SetCourse(&race.Course{Gates: []*race.Gate{
+		{Number: 1, Position: mgl32.Vec3{0, 0, gateApproach}},
+	}})
+	if err := run(t, e, 10*time.Second); err != nil {
+		t.Fatal(err)
+	}
+	if z := odometry.Pose().Position.Z(); z < gateApproach+gateExit-0.5 {
+		t.Errorf("ended at z %v before the gate", z)
+	}
+}
+
+func TestExecutorGateErrors(t *testing.T) {
+	e, _, _ := simulate(&Step{Type: StepGate, Gate: 1})
+	if err := run(t, e, time.Second); err == nil || e.State() != Failed {
+		t.Errorf("no course: got %v, %v", err, e.State())
+	}
+
+	e, _, _ = simulate(&Step{Type: StepGate, Gate: 2})
+	e.SetCourse(&race.Course{Gates: []*race.Gate{{Number: 1}}})
+	if err := run(t, e, time.Second); err == nil || !strings.Contains(err.Error(), "no gate 2") {
+		t.Errorf("unknown gate: got %v", err)
+	}
+}
+
+func TestExecutorTimeout(t *testing.T) {
+	e, d, _ := simulate(
+		&Step{Type: StepTakeOff},
+		&Step{Type: StepGoTo, Position: mgl32.Vec3{0, 0, 10}, Timeout: 0.3},
+	)
+	err := run(t, e, 5*time.Second)
+	if err == nil || !strings.Contains(err.Error(), "timed out") || e.State() != Failed {
+		t.Fatalf("got %v, %v, want a timeout", err, e.State())
+	}
+	if v := d.GetVelocity(); v != (mgl32.Vec4{}) {
+		t.Errorf("still moving with %v after the timeout", v)
+	}
+}
+
+func TestExecutorAbort(t *testing.T) {
+	e, d, _ := simulate(
+		&Step{Type: StepTakeOff},
+		&Step{Type: StepHover, Duration: 30},
+	)
+	go func() {
+		time.Sleep(300 * time.Millisecond)
+		e.Abort()
+	}()
+	if err := run(t, e, 5*time.Second); err != ErrAborted {
+		t.Fatalf("got %v, want %v", err, ErrAborted)
+	}
+	if e.State() != Aborted || flying(d) {
+		t.Errorf("state %v, flying %v after the abort", e.State(), flying(d))
+	}
+}
+
+func TestExecutorPause(t *testing.T) {
+	e, _, _ := simulate(&Step{Type: StepHover, Duration: 0.3})
+	go func() {
+		time.Sleep(100 * time.Millisecond)
+		e.Pause()
+		time.Sleep(500 * time.Millisecond)
+		if e.State() != Paused {
+			t.Errorf("state %v while paused", e.State())
+		}
+		e.Resume()
+	}()
+
+	// the mission clock stops while paused
+	start := time.Now()
+	if err := run(t, e, 5*time.Second); err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(start); elapsed < 750*time.Millisecond {
+		t.Errorf("hover of 0.3s with a 0.5s pause took %v", elapsed)
+	}
+}
+
+func TestMissionValidate(t *testing.T) {
+	tests := []struct {
+		step  Step
+		valid bool
+	}{
+		{Step{Type: StepTakeOff}, true},
+		{Step{Type: StepHover, Duration: 1}, true},
+		{Step{Type: StepHover}, false},
+		{Step{Type: StepGate, Gate: 1}, true},
+		{Step{Type: StepGate}, false},
+		{Step{Type: "flip"}, false},
+		{Step{Type: StepGoTo, Tolerance: -1}, false},
+	}
+	for _, test := range tests {
+		step := test.step
+		m := &Mission{Steps: []*Step{&step}}
+		if err := m.Validate(); (err == nil) != test.valid {
+			t.Errorf("%s: got %v, want valid %v", &step, err, test.valid)
+		}
+	}
+	if err := (&Mission{}).Validate(); err == nil {
+		t.Error("mission without steps is valid")
+	}
+}
@@ -0,0 +1,116 @@
+package mission
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/go-gl/mathgl/mgl32"
+)
+
+type StepType string
+
+const (
+	StepTakeOff StepType = "takeoff"
+	StepGoTo    StepType = "goto"
+	StepYaw     StepType = "yaw"
+	StepHover   StepType = "hover"
+	StepGate    StepType = "gate"
+	StepLand    StepType = "land"
+)
+
+// Step is a single instruction of a mission
+type Step struct {
+	Type StepType `json:"type"`
+
+	// goto: target position (m), in world coordinates or, if relative, in
+	// drone coordinates at the start of the step
+	Position mgl32.Vec3 `json:"position,omitempty"`
+	Relative bool       `json:"relative,omitempty"`
+
+	// yaw: heading (degrees) in the world frame, or the clockwise turn if
+	// relative
+	Yaw float32 `json:"yaw,omitempty"`
+
+	// hover: how long to hover (s)
+	Duration float32 `json:"duration,omitempty"`
+
+	// gate: number of the course gate to fly through
+	Gate int `json:"gate,omitempty"`
+
+	// Tolerance overrides how close (m or degrees) the target must be reached
+	Tolerance float32 `json:"tolerance,omitempty"`
+
+	// Timeout overrides how long (s) the step may take before the mission fails
+	Timeout float32 `json:"timeout,omitempty"`
+}
+
+func (s *Step) String() string {
+	switch s.Type {
+	case StepGoTo:
+		if s.Relative {
+			return fmt.Sprintf("goto %v relative", s.Position)
+		}
+		return fmt.Sprintf("goto %v", s.Position)
+	case StepYaw:
+		if s.Relative {
+			return fmt.Sprintf("yaw %.0f relative", s.Yaw)
+		}
+		return fmt.Sprintf("yaw %.0f", s.Yaw)
+	case StepHover:
+		return fmt.Sprintf("hover %.1fs", s.Duration)
+	case StepGate:
+		return fmt.Sprintf("gate %d", s.Gate)
+	}
+	return string(s.Type)
+}
+
+// Mission is an ordered list of steps
+type Mission struct {
+	Name  string  `json:"name"`
+	Steps []*Step `json:"steps"`
+}
+
+// Load reads a mission from a json file
+func Load(filename string) (*Mission, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	m := &Mission{}
+	if err := json.NewDecoder(f).Decode(m); err != nil {
+		return nil, fmt.Errorf("mission %s: %v", filename, err)
+	}
+	if err := m.Validate(); err != nil {
+		return nil, fmt.Errorf("mission %s: %v", filename, err)
+	}
+	return m, nil
+}
+
+// Validate checks that all steps are known and complete
+func (m *Mission) Validate() error {
+	if len(m.Steps) == 0 {
+		return fmt.Errorf("no steps")
+	}
+	for i, s := range m.Steps {
+		switch s.Type {
+		case StepTakeOff, StepGoTo, StepYaw, StepLand:
+		case StepHover:
+			if s.Duration <= 0 {
+				return fmt.Errorf("step %d: hover needs a duration", i+1)
+			}
+		case StepGate:
+			if s.Gate <= 0 {
+				return fmt.Errorf("step %d: gate needs a gate number", i+1)
+			}
+		default:
+			return fmt.Errorf("step %d: unknown type %q", i+1, s.Type)
+		}
+		if s.Tolerance < 0 || s.Timeout < 0 {
+			return fmt.Errorf("step %d: negative tolerance or timeout", i+1)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,165 @@
+package race
+
+import (
+	"fmt"
+	"time"
+)
+
+// Config describes the rings and how long lost markers are tracked
+type Config struct {
+	MarkerSize      float32 `yaml:"markerSize"`      // m, side length of a marker
+	MarkerOffset    float32 `yaml:"markerOffset"`    // m, from the ring center to a marker center
+	RingRadius      float32 `yaml:"ringRadius"`      // m, a gate is only passed within it
+	LostFrames      int     `yaml:"lostFrames"`      // frames a marker is tracked without detection
+	TrackerRectSize float32 `yaml:"trackerRectSize"` // corner tracker size relative to the marker
+
+	Search     SearchConfig     `yaml:"search"`
+	Trajectory TrajectoryConfig `yaml:"trajectory"`
+}
+
+func DefaultConfig() Config {
+	return Config{
+		MarkerSize:      0.08,
+		MarkerOffset:    0.295,
+		RingRadius:      0.22,
+		LostFrames:      30,
+		TrackerRectSize: 0.4,
+		Search:          DefaultSearchConfig(),
+		Trajectory:      DefaultTrajectoryConfig(),
+	}
+}
+
+func (c Config) Validate() error {
+	if c.MarkerSize <= 0 || c.MarkerOffset <= 0 || c.RingRadius <= 0 {
+		return fmt.Errorf("invalid ring geometry, marker size %v, offset %v, radius %v", c.MarkerSize, c.MarkerOffset, c.RingRadius)
+	}
+	if c.LostFrames < 0 || c.TrackerRectSize <= 0 {
+		return fmt.Errorf("invalid marker tracking, lost frames %d, tracker size %v", c.LostFrames, c.TrackerRectSize)
+	}
+	if err := c.Search.Validate(); err != nil {
+		return err
+	}
+	return c.Trajectory.Validate()
+}
+
+// SearchConfig describes how the next gate is searched when none is visible.
+// Speeds are fractions of full power, MaxSpeed and YawRate convert them to
+// distances and angles.
+type SearchConfig struct {
+	Behaviours  []string      `yaml:"behaviours"`  // tried in order
+	Timeout     time.Duration `yaml:"timeout"`     // per behaviour
+	GiveUpAfter time.Duration `yaml:"giveUpAfter"` // for the whole search
+	GiveUp      string        `yaml:"giveUp"`      // land or hover
+
+	MaxSpeed   float32       `yaml:"maxSpeed"` // m/s at full power
+	YawRate    float32       `yaml:"yawRate"`  // rad/s at full power
+	YawSpeed   float32       `yaml:"yawSpeed"`
+	SweepStep  float32       `yaml:"sweepStep"`  // deg turned between dwells
+	Dwell      time.Duration `yaml:"dwell"`      // hovering to let the detection catch up
+	SweepAngle float32       `yaml:"sweepAngle"` // deg to either side of the last known direction
+	MaxAge     time.Duration `yaml:"maxAge"`     // of the last known direction
+
+	ClimbSpeed float32 `yaml:"climbSpeed"`
+	ClimbStep  float32 `yaml:"climbStep"` // m between sweeps
+	ClimbSteps int     `yaml:"climbSteps"`
+	MaxHeight  float32 `yaml:"maxHeight"` // m
+
+	Speed float32 `yaml:"speed"` // along the expanding square
+	Leg   float32 `yaml:"leg"`   // m, first leg of the square
+	Legs  int     `yaml:"legs"`
+}
+
+func DefaultSearchConfig() SearchConfig {
+	return SearchConfig{
+		Behaviours:  []string{SearchLastKnown, SearchYawSweep, SearchClimbAndSweep, SearchSquare},
+		Timeout:     30 * time.Second,
+		GiveUpAfter: 90 * time.Second,
+		GiveUp:      "land",
+		MaxSpeed:    1.0,
+		YawRate:     1.7,
+		YawSpeed:    0.4,
+		SweepStep:   45,
+		Dwell:       time.Second,
+		SweepAngle:  30,
+		MaxAge:      5 * time.Second,
+		ClimbSpeed:  0.3,
+		ClimbStep:   0.4,
+		ClimbSteps:  2,
+		MaxHeight:   2.5,
+		Speed:       0.3,
+		Leg:         0.5,
+		Legs:        6,
+	}
+}
+
+func (c SearchConfig) Validate() error {
+	if len(c.Behaviours) == 0 {
+		return fmt.Errorf("no search behaviours")
+	}
+	for _, b := range c.Behaviours {
+		switch b {
+		case SearchLastKnown, SearchYawSweep, SearchClimbAndSweep, SearchSquare:
+		default:
+			return fmt.Errorf("unknown search behaviour %q", b)
+		}
+	}
+	if c.GiveUp != "land" && c.GiveUp != "hover" {
+		return fmt.Errorf("invalid search give up policy %q, use land or hover", c.GiveUp)
+	}
+	if c.Timeout <= 0 || c.GiveUpAfter <= 0 {
+		return fmt.Errorf("invalid search timeouts %v, %v", c.Timeout, c.GiveUpAfter)
+	}
+	if c.MaxSpeed <= 0 || c.YawRate <= 0 || c.YawSpeed <= 0 || c.ClimbSpeed <= 0 || c.Speed <= 0 {
+		return fmt.Errorf("search speeds must be positive")
+	}
+	if c.SweepStep <= 0 || c.SweepAngle < 0 || c.ClimbStep <= 0 || c.Leg <= 0 {
+		return fmt.Errorf("invalid search pattern, sweep step %v, sweep angle %v, climb step %v, leg %v", c.SweepStep, c.SweepAngle, c.ClimbStep, c.Leg)
+	}
+	return nil
+}
+
+// TrajectoryConfig describes the paths planned through the gates of a course
+// and how they are followed
+type TrajectoryConfig struct {
+	Gates      int           `yaml:"gates"`      // planned ahead
+	Speed      float32       `yaml:"speed"`      // m/s through the gates
+	Exit       float32       `yaml:"exit"`       // m flown beyond the last gate
+	MinSegment time.Duration `yaml:"minSegment"` // shortest time between gates
+	Replan     time.Duration `yaml:"replan"`     // interval, besides after every gate
+	Lookahead  time.Duration `yaml:"lookahead"`  // compensates the command latency
+	Tolerance  float32       `yaml:"tolerance"`  // m from the end of the course
+
+	MaxSpeed     float32 `yaml:"maxSpeed"`     // m/s at full power
+	MaxCommand   float32 `yaml:"maxCommand"`   // -1.0 to 1.0
+	PositionGain float32 `yaml:"positionGain"` // m/s per m of error
+	YawGain      float32 `yaml:"yawGain"`      // per rad
+}
+
+func DefaultTrajectoryConfig() TrajectoryConfig {
+	return TrajectoryConfig{
+		Gates:        3,
+		Speed:        0.6,
+		Exit:         0.5,
+		MinSegment:   time.Second,
+		Replan:       time.Second,
+		Lookahead:    200 * time.Millisecond,
+		Tolerance:    0.2,
+		MaxSpeed:     1.0,
+		MaxCommand:   0.8,
+		PositionGain: 0.8,
+		YawGain:      1.0,
+	}
+}
+
+func (c TrajectoryConfig) Validate() error {
+	if c.Gates < 1 {
+		return fmt.Errorf("trajectory has to plan at least one gate ahead, not %d", c.Gates)
+	}
+	if c.Speed <= 0 || c.MaxSpeed <= 0 || c.MaxCommand <= 0 || c.MaxCommand > 1 {
+		return fmt.Errorf("invalid trajectory speeds, speed %v, max speed %v, max command %v", c.Speed, c.MaxSpeed, c.MaxCommand)
+	}
+	if c.MinSegment <= 0 || c.Replan <= 0 || c.Lookahead < 0 || c.Exit < 0 || c.Tolerance <= 0 {
+		return fmt.Errorf("invalid trajectory timing or distances")
+	}
+	return nil
+}
@@ -0,0 +1,68 @@
+package race
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/go-gl/mathgl/mgl32"
+	"gocv.io/x/gocv/contrib"
+	"tellobot/drone"
+	"tellobot/localization"
+)
+
+// Gate is a ring placed at a known pose in the world
+type Gate struct {
+	Number   int        `json:"number"`
+	Position mgl32.Vec3 `json:"position"` // ring center in world coordinates (m)
+	Rotation mgl32.Vec3 `json:"rotation"` // rotation around x, y and z axis (degrees)
+}
+
+// Normal returns the direction the gate is flown through in world coordinates
+func (g *Gate) Normal() mgl32.Vec3 {
+	return localization.RotationFromDegrees(g.Rotation).Mul3x1(mgl32.Vec3{0, 0, 1})
+}
+
+// RelativeTo returns the gate center in drone coordinates
+func (g *Gate) RelativeTo(pose *localization.Pose) mgl32.Vec3 {
+	return pose.ToDrone(g.Position)
+}
+
+// Course is the ordered list of gates of a race
+type Course struct {
+	Gates []*Gate `json:"gates"`
+}
+
+// LoadCourse reads a course definition from a json file
+func LoadCourse(filename string) (*Course, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	c := &Course{}
+	if err := json.NewDecoder(f).Decode(c); err != nil {
+		return nil, fmt.Errorf("course %s: %v", filename, err)
+	}
+	return c, nil
+}
+
+// Gate returns the gate with the given number
+func (c *Course) Gate(number int) (*Gate, bool) {
+	for _, g := range c.Gates {
+		if g.Number == number {
+			return g, true
+		}
+	}
+	return nil, false
+}
+
+// WorldPose returns the ring position and rotation in world coordinates given
+// the drone pose. EstimatePose must have been called first.
+func (r *Ring) WorldPose(d drone.Drone, pose *localization.Pose) (pos mgl32.Vec3, rot mgl32.Mat3) {
+	camToWorld := pose.Rotation.Mul3(d.CameraToDroneMatrix())
+	pos = pose.Position.Add(camToWorld.Mul3x1(r.Position))
+	rot = camToWorld.Mul3(contrib.Rodrigues(r.RodriguesRotation))
+	return pos, rot
+}
@@ -6,44 +6,72 @@ import (
 	"image/color"
 	"math"
 	"tellobot/drone"
+	"tellobot/logging"
+	"tellobot/metrics"
+	"tellobot/tracking"
+	"time"
 
 	"github.com/go-gl/mathgl/mgl32"
 	"gocv.io/x/gocv"
 	"gocv.io/x/gocv/contrib"
 )
 
-var markerPositions []mgl32.Vec3 = []mgl32.Vec3{
-	mgl32.Vec3{0.0, 0.295, 0},
-	mgl32.Vec3{0.295, 0.0, 0},
-	mgl32.Vec3{0.0, -0.295, 0},
-	mgl32.Vec3{-0.295, 0.0, 0},
-}
+var log = logging.Default.With("race")
+
+var (
+	detectDuration = metrics.Default.NewHistogram("tellobot_detect_rings_seconds", "Time to detect and track rings in a frame.", metrics.DefaultBuckets)
+	ringsDetected  = metrics.Default.NewCounter("tellobot_rings_detected_total", "Rings found in video frames.", "")
+	poseFailures   = metrics.Default.NewCounter("tellobot_solvepnp_failures_total", "Ring pose estimations without a usable result.", "")
+)
 
-var projectPoints []mgl32.Vec3 = []mgl32.Vec3{
-	mgl32.Vec3{0.0, 0.0, 0.0},
-	mgl32.Vec3{0.0, 0.0, 0.2},
-	mgl32.Vec3{0.0, 0.0, 0.4},
-	markerPositions[0],
-	markerPositions[1],
-	markerPositions[2],
-	markerPositions[3],
+// geometry are the points of a ring in ring coordinates
+type geometry struct {
+	markerPositions []mgl32.Vec3 // N, E, S, W
+	markerCorners   []mgl32.Vec3 // NW, NE, SE, SW relative to a marker
+	projectPoints   []mgl32.Vec3 // center, z-axis, markers and the ring outline
 }
 
-func init() {
+func newGeometry(config Config) *geometry {
+	o := config.MarkerOffset
+	h := config.MarkerSize / 2
+	g := &geometry{
+		markerPositions: []mgl32.Vec3{
+			mgl32.Vec3{0.0, o, 0},
+			mgl32.Vec3{o, 0.0, 0},
+			mgl32.Vec3{0.0, -o, 0},
+			mgl32.Vec3{-o, 0.0, 0},
+		},
+		markerCorners: []mgl32.Vec3{
+			mgl32.Vec3{-h, +h, 0},
+			mgl32.Vec3{+h, +h, 0},
+			mgl32.Vec3{+h, -h, 0},
+			mgl32.Vec3{-h, -h, 0},
+		},
+	}
+
+	g.projectPoints = []mgl32.Vec3{
+		mgl32.Vec3{0.0, 0.0, 0.0},
+		mgl32.Vec3{0.0, 0.0, 0.2},
+		mgl32.Vec3{0.0, 0.0, 0.4},
+	}
+	g.projectPoints = append(g.projectPoints, g.markerPositions...)
 	for i := 0; i < 40; i++ {
 		angle := (2.0 * math.Pi) * (float64(i) / 40.0)
 		s := float32(math.Sin(angle))
 		c := float32(math.Cos(angle))
-		projectPoints = append(projectPoints, mgl32.Vec3{s * 0.22, c * 0.22, 0.0})
+		g.projectPoints = append(g.projectPoints, mgl32.Vec3{s * config.RingRadius, c * config.RingRadius, 0.0})
 	}
+	return g
 }
 
 type Race struct {
-	dict contrib.ArucoDictionary
+	dict     contrib.ArucoDictionary
+	config   Config
+	geometry *geometry
 }
 
-func NewRace() *Race {
-	r := Race{}
+func NewRace(config Config) *Race {
+	r := Race{config: config, geometry: newGeometry(config)}
 	r.dict = contrib.NewArucoPredefinedDictionary(contrib.ArucoPredefinedDict_5x5_50)
 
 	return &r
@@ -76,6 +104,9 @@ func findSize(corners []mgl32.Vec2) mgl32.Vec2 {
 }
 
 func (r *Race) DetectRings(img *gocv.Mat, rings map[int]*Ring) map[int]*Ring {
+	start := time.Now()
+	defer detectDuration.ObserveSince(start)
+
 	tracking := false
 	if rings != nil {
 		tracking = true
@@ -91,7 +122,7 @@ func (r *Race) DetectRings(img *gocv.Mat, rings map[int]*Ring) map[int]*Ring {
 				continue
 			}
 			m.DetectedAgo++
-			if m.DetectedAgo > 30 {
+			if m.DetectedAgo > r.config.LostFrames {
 				for j := 0; j < 4; j++ {
 					if m.Trackers[j] != nil {
 						m.Trackers[j].Close()
@@ -111,7 +142,7 @@ func (r *Race) DetectRings(img *gocv.Mat, rings map[int]*Ring) map[int]*Ring {
 		}
 	}
 
-	const trackerRectSize = 0.4
+	trackerRectSize := r.config.TrackerRectSize
 
 	// add / update newly detected
 	corners, ids := r.dict.DetectMarkers(img)
@@ -119,7 +150,7 @@ func (r *Race) DetectRings(img *gocv.Mat, rings map[int]*Ring) map[int]*Ring {
 		ringId := (id - 1) / 4
 		ring, ok := rings[ringId]
 		if !ok {
-			ring = &Ring{}
+			ring = &Ring{geometry: r.geometry}
 			rings[ringId] = ring
 		}
 		m := ring.Markers[(id-1)%4]
@@ -204,22 +235,16 @@ func (r *Race) DetectRings(img *gocv.Mat, rings map[int]*Ring) map[int]*Ring {
 				}
 			}
 			if markersActive == 0 || totalCorners < 4 {
-				fmt.Println(markersActive, totalCorners)
+				log.Debug("ring lost", logging.Fields{"ring": id, "markers": markersActive, "corners": totalCorners})
 				delete(rings, id)
 			}
 		}
 	}
 
+	ringsDetected.Add("", float64(len(rings)))
 	return rings
 }
 
-var markerCorners []mgl32.Vec3 = []mgl32.Vec3{
-	mgl32.Vec3{-0.04, +0.04, 0},
-	mgl32.Vec3{+0.04, +0.04, 0},
-	mgl32.Vec3{+0.04, -0.04, 0},
-	mgl32.Vec3{-0.04, -0.04, 0},
-}
-
 func (r *Ring) EstimatePose(d drone.Drone) (pos mgl32.Vec3, rot mgl32.Mat3) {
 	var objectPoints []mgl32.Vec3
 	var imagePoints []mgl32.Vec2
@@ -229,24 +254,69 @@ func (r *Ring) EstimatePose(d drone.Drone) (pos mgl32.Vec3, rot mgl32.Mat3) {
 		}
 		for j := 0; j < 4; j++ {
 			if m.DetectedAgo == 0 || m.Trackers[j] != nil {
-				objectPoints = append(objectPoints, markerPositions[i].Add(markerCorners[j]))
+				objectPoints = append(objectPoints, r.geometry.markerPositions[i].Add(r.geometry.markerCorners[j]))
 				imagePoints = append(imagePoints, m.Corners[j])
 			}
 		}
 	}
 
 	r.RodriguesRotation, r.Position = contrib.SolvePnP(objectPoints, imagePoints, d.CameraMatrix(), d.DistortionCoefficients())
+	if len(objectPoints) < 4 || !finite(r.Position) || r.Position.Z() <= 0 {
+		poseFailures.Inc()
+	}
 
 	rot = contrib.Rodrigues(r.RodriguesRotation)
+	log.Debug("ring pose", logging.Fields{"position": r.Position, "rotation": r.RodriguesRotation, "points": len(objectPoints)})
 
 	return r.Position, rot
 }
 
+// Detection returns the ring as a target to follow, its pose is the one of
+// the last EstimatePose
+func (r *Ring) Detection(d drone.Drone) tracking.Detection {
+	var corners []mgl32.Vec2
+	for _, m := range r.Markers {
+		if m != nil {
+			corners = append(corners, m.Corners...)
+		}
+	}
+	normal := contrib.Rodrigues(r.RodriguesRotation).Mul3x1(mgl32.Vec3{0, 0, 1})
+	return tracking.Detection{
+		Box:        tracking.BoundingBox(corners),
+		Confidence: 1,
+		HasPose:    true,
+		Position:   d.CameraToDroneMatrix().Mul3x1(r.Position),
+		Rotation:   normal.X(),
+	}
+}
+
+// Nearest returns the ring closest to the camera
+func Nearest(rings map[int]*Ring) (*Ring, bool) {
+	var nearest *Ring
+	for _, ring := range rings {
+		if nearest == nil || ring.Position.Len() < nearest.Position.Len() {
+			nearest = ring
+		}
+	}
+	return nearest, nearest != nil
+}
+
+func finite(v mgl32.Vec3) bool {
+	for _, x := range v {
+		if math.IsNaN(float64(x)) || math.IsInf(float64(x), 0) {
+			return false
+		}
+	}
+	return true
+}
+
 type Ring struct {
 	Markers [4]*Marker // N, E, S, W
 
 	Position          mgl32.Vec3
 	RodriguesRotation mgl32.Vec3
+
+	geometry *geometry
 }
 
 type Marker struct {
@@ -257,7 +327,7 @@ type Marker struct {
 
 func (r *Ring) Draw(img *gocv.Mat, d drone.Drone) {
 
-	p := contrib.ProjectPoints(projectPoints, r.RodriguesRotation, r.Position, d.CameraMatrix(), d.DistortionCoefficients())
+	p := contrib.ProjectPoints(r.geometry.projectPoints, r.RodriguesRotation, r.Position, d.CameraMatrix(), d.DistortionCoefficients())
 	center := image.Pt(int(p[0][0]), int(p[0][1]))
 	z := image.Pt(int(p[1][0]), int(p[1][1]))
 	z2 := image.Pt(int(p[2][0]), int(p[2][1]))
@@ -0,0 +1,243 @@
+package race
+
+import (
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/go-gl/mathgl/mgl32"
+	"tellobot/logging"
+)
+
+// Search behaviours, in the order of SearchConfig.Behaviours
+const (
+	SearchLastKnown     = "last-known"
+	SearchYawSweep      = "yaw-sweep"
+	SearchClimbAndSweep = "climb-and-sweep"
+	SearchSquare        = "expanding-square"
+)
+
+// SearchState is the outcome of a search update
+type SearchState int
+
+const (
+	Searching SearchState = iota
+	SearchGaveUp
+)
+
+// segment is a velocity held for a duration
+type segment struct {
+	velocity mgl32.Vec4 // right, up, forward, clockwise
+	duration time.Duration
+}
+
+// plan builds the segments of a behaviour when it starts, nil if it has
+// nothing to try
+type plan func(now time.Time) []segment
+
+// Searcher looks for the next gate when none is visible. It tries the
+// configured behaviours in turn, each for at most the behaviour timeout,
+// and gives up after the total timeout or when all are done. If none of them
+// has anything to try it hovers until the total timeout. The behaviours are
+// open loop and only depend on the time passed to Update, so they run the
+// same on the fake drone.
+type Searcher struct {
+	mutex  sync.Mutex
+	config SearchConfig
+	plans  []plan
+	names  []string
+
+	started   bool
+	start     time.Time
+	current   int
+	segments  []segment
+	planStart time.Time
+	tried     bool // a behaviour had something to try
+	gaveUp    bool
+
+	// where the last ring was seen, for the last-known search
+	lastBearing float32 // rad, clockwise from forward
+	lastSeen    time.Time
+}
+
+func NewSearcher(config SearchConfig) (*Searcher, error) {
+	s := &Searcher{config: config}
+	for _, name := range config.Behaviours {
+		var p plan
+		switch name {
+		case SearchLastKnown:
+			p = s.lastKnown
+		case SearchYawSweep:
+			p = s.yawSweep
+		case SearchClimbAndSweep:
+			p = s.climbAndSweep
+		case SearchSquare:
+			p = s.expandingSquare
+		default:
+			return nil, fmt.Errorf("unknown search behaviour %q", name)
+		}
+		s.plans = append(s.plans, p)
+		s.names = append(s.names, name)
+	}
+	return s, nil
+}
+
+// Seen remembers the direction of a ring in drone coordinates and ends a
+// running search
+func (s *Searcher) Seen(pos mgl32.Vec3, now time.Time) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.lastBearing = float32(math.Atan2(float64(pos.X()), float64(pos.Z())))
+	s.lastSeen = now
+	s.started = false
+}
+
+// Reset ends a running search
+func (s *Searcher) Reset() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.started = false
+}
+
+// Update returns the velocity to search with at now. height in m keeps
+// climbing behaviours below the maximum height.
+func (s *Searcher) Update(now time.Time, height float32) (mgl32.Vec4, SearchState) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if !s.started {
+		s.started = true
+		s.start = now
+		s.current = -1
+		s.tried = false
+		s.gaveUp = false
+		s.next(now)
+	}
+	if now.Sub(s.start) > s.config.GiveUpAfter {
+		return mgl32.Vec4{}, s.giveUp("timeout")
+	}
+
+	for s.current < len(s.plans) {
+		elapsed := now.Sub(s.planStart)
+		if elapsed <= s.config.Timeout {
+			for _, seg := range s.segments {
+				if elapsed < seg.duration {
+					v := seg.velocity
+					if v[1] > 0 && height >= s.config.MaxHeight {
+						v[1] = 0
+					}
+					return v, Searching
+				}
+				elapsed -= seg.duration
+			}
+		}
+		s.next(now)
+	}
+	if !s.tried {
+		return mgl32.Vec4{}, Searching
+	}
+	return mgl32.Vec4{}, s.giveUp("all behaviours done")
+}
+
+// Land returns true if the drone should land once the search gave up,
+// otherwise it hovers
+func (s *Searcher) Land() bool {
+	return s.config.GiveUp == "land"
+}
+
+func (s *Searcher) giveUp(reason string) SearchState {
+	if !s.gaveUp {
+		s.gaveUp = true
+		log.Warn("search gave up", logging.Fields{"reason": reason, "policy": s.config.GiveUp})
+	}
+	return SearchGaveUp
+}
+
+// next starts the next behaviour with something to try
+func (s *Searcher) next(now time.Time) {
+	for s.current++; s.current < len(s.plans); s.current++ {
+		s.segments = s.plans[s.current](now)
+		if len(s.segments) > 0 {
+			s.planStart = now
+			s.tried = true
+			log.Info("search", logging.Fields{"behaviour": s.names[s.current]})
+			return
+		}
+	}
+}
+
+// turn returns the segment turning by angle rad, clockwise positive
+func (s *Searcher) turn(angle float64) segment {
+	c := s.config
+	speed := c.YawSpeed
+	if angle < 0 {
+		speed, angle = -speed, -angle
+	}
+	seconds := angle / (float64(c.YawSpeed) * float64(c.YawRate))
+	return segment{mgl32.Vec4{0, 0, 0, speed}, time.Duration(seconds * float64(time.Second))}
+}
+
+// sweep turns a full circle in steps, hovering after each
+func (s *Searcher) sweep() []segment {
+	step := float64(mgl32.DegToRad(s.config.SweepStep))
+	var segments []segment
+	for turned := 0.0; turned < 2*math.Pi-1e-6; turned += step {
+		segments = append(segments, s.turn(step), segment{duration: s.config.Dwell})
+	}
+	return segments
+}
+
+func (s *Searcher) yawSweep(now time.Time) []segment {
+	return s.sweep()
+}
+
+// climbAndSweep climbs a step and sweeps, repeatedly, the climb stops at the
+// maximum height
+func (s *Searcher) climbAndSweep(now time.Time) []segment {
+	c := s.config
+	climb := segment{
+		mgl32.Vec4{0, c.ClimbSpeed, 0, 0},
+		time.Duration(float64(c.ClimbStep/(c.ClimbSpeed*c.MaxSpeed)) * float64(time.Second)),
+	}
+	var segments []segment
+	for i := 0; i < c.ClimbSteps; i++ {
+		segments = append(segments, climb)
+		segments = append(segments, s.sweep()...)
+	}
+	return segments
+}
+
+// expandingSquare flies legs of growing length with a right turn after each:
+// 1, 1, 2, 2, 3, 3 ... legs
+func (s *Searcher) expandingSquare(now time.Time) []segment {
+	c := s.config
+	var segments []segment
+	for i := 0; i < c.Legs; i++ {
+		length := c.Leg * float32(i/2+1)
+		seconds := float64(length / (c.Speed * c.MaxSpeed))
+		segments = append(segments,
+			segment{mgl32.Vec4{0, 0, c.Speed, 0}, time.Duration(seconds * float64(time.Second))},
+			segment{duration: c.Dwell},
+			s.turn(math.Pi/2))
+	}
+	return segments
+}
+
+// lastKnown turns towards where the last ring was seen and sweeps around
+// that direction, nothing if there was no ring recently
+func (s *Searcher) lastKnown(now time.Time) []segment {
+	c := s.config
+	if s.lastSeen.IsZero() || now.Sub(s.lastSeen) > c.MaxAge {
+		return nil
+	}
+	sweep := float64(mgl32.DegToRad(c.SweepAngle))
+	return []segment{
+		s.turn(float64(s.lastBearing)),
+		{duration: c.Dwell},
+		s.turn(-sweep),
+		{duration: c.Dwell},
+		s.turn(2 * sweep),
+		{duration: c.Dwell},
+	}
+}
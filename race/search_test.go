@@ -0,0 +1,204 @@
+package race
+
+import (
+	"testing"
+	"time"
+
+	"github.com/go-gl/mathgl/mgl32"
+)
+
+// searchConfig turns a quarter circle in pi seconds and flies the first leg
+// of a square in a second
+func searchConfig(behaviours ...string) SearchConfig {
+	c := DefaultSearchConfig()
+	c.Behaviours = behaviours
+	c.Timeout = 30 * time.Second
+	c.GiveUpAfter = 90 * time.Second
+	c.YawRate = 1
+	c.YawSpeed = 0.5
+	c.SweepStep = 90
+	c.Dwell = time.Second
+	c.MaxSpeed = 1
+	c.Speed = 0.5
+	c.Leg = 0.5
+	c.ClimbSpeed = 0.5
+	c.ClimbStep = 0.5
+	c.MaxHeight = 2
+	return c
+}
+
+type searchStep struct {
+	at     time.Duration
+	height float32
+	want   mgl32.Vec4
+	state  SearchState
+}
+
+func TestSearcherUpdate(t *testing.T) {
+	turn := mgl32.Vec4{0, 0, 0, 0.5}
+	hover := mgl32.Vec4{}
+
+	tests := []struct {
+		name   string
+		config SearchConfig
+		seen   *mgl32.Vec3  // ring position at the start
+		steps  []searchStep // times from the first update
+	}{
+		{
+			name:   "yaw sweep turns and dwells",
+			config: searchConfig(SearchYawSweep),
+			steps: []searchStep{
+				{at: 0, want: turn},
+				{at: 3 * time.Second, want: turn},
+				{at: 3500 * time.Millisecond, want: hover},
+				{at: 4500 * time.Millisecond, want: turn},
+			},
+		},
+		{
+			name:   "expanding square flies a leg, dwells and turns right",
+			config: searchConfig(SearchSquare),
+			steps: []searchStep{
+				{at: 0, want: mgl32.Vec4{0, 0, 0.5, 0}},
+				{at: 500 * time.Millisecond, want: mgl32.Vec4{0, 0, 0.5, 0}},
+				{at: 1500 * time.Millisecond, want: hover},
+				{at: 2500 * time.Millisecond, want: turn},
+			},
+		},
+		{
+			name:   "climb and sweep climbs first",
+			config: searchConfig(SearchClimbAndSweep),
+			steps: []searchStep{
+				{at: 0, height: 1, want: mgl32.Vec4{0, 0.5, 0, 0}},
+				{at: 1500 * time.Millisecond, height: 1.5, want: turn},
+			},
+		},
+		{
+			name:   "climb stops at the maximum height",
+			config: searchConfig(SearchClimbAndSweep),
+			steps: []searchStep{
+				{at: 0, height: 2, want: hover},
+				{at: 500 * time.Millisecond, height: 2.5, want: hover},
+			},
+		},
+		{
+			name:   "last known turns clockwise to a ring on the right",
+			config: searchConfig(SearchLastKnown),
+			seen:   &mgl32.Vec3{1, 0, 1},
+			steps: []searchStep{
+				{at: 0, want: turn},
+				{at: 500 * time.Millisecond, want: turn},
+				{at: 2 * time.Second, want: hover},
+			},
+		},
+		{
+			name:   "last known turns counter clockwise to a ring on the left",
+			config: searchConfig(SearchLastKnown),
+			seen:   &mgl32.Vec3{-1, 0, 1},
+			steps: []searchStep{
+				{at: 0, want: turn.Mul(-1)},
+				{at: 500 * time.Millisecond, want: turn.Mul(-1)},
+			},
+		},
+		{
+			name:   "last known is done after its sweep",
+			config: searchConfig(SearchLastKnown),
+			seen:   &mgl32.Vec3{1, 0, 1},
+			steps: []searchStep{
+				{at: 0, want: turn},
+				{at: 8 * time.Second, want: hover, state: SearchGaveUp},
+			},
+		},
+		{
+			name:   "last known without a ring hovers until the total timeout",
+			config: searchConfig(SearchLastKnown),
+			steps: []searchStep{
+				{at: 0, want: hover},
+				{at: 60 * time.Second, want: hover},
+				{at: 91 * time.Second, want: hover, state: SearchGaveUp},
+			},
+		},
+		{
+			name:   "last known is skipped without a ring",
+			config: searchConfig(SearchLastKnown, SearchSquare),
+			steps: []searchStep{
+				{at: 0, want: mgl32.Vec4{0, 0, 0.5, 0}},
+			},
+		},
+		{
+			name: "the next behaviour starts on timeout",
+			config: func() SearchConfig {
+				c := searchConfig(SearchYawSweep, SearchSquare)
+				c.Timeout = 2 * time.Second
+				return c
+			}(),
+			steps: []searchStep{
+				{at: 0, want: turn},
+				{at: 2500 * time.Millisecond, want: mgl32.Vec4{0, 0, 0.5, 0}},
+				{at: 3 * time.Second, want: mgl32.Vec4{0, 0, 0.5, 0}},
+				{at: 4 * time.Second, want: hover},
+				{at: 5 * time.Second, want: hover, state: SearchGaveUp},
+			},
+		},
+		{
+			name: "the search gives up after the total timeout",
+			config: func() SearchConfig {
+				c := searchConfig(SearchYawSweep)
+				c.GiveUpAfter = 10 * time.Second
+				return c
+			}(),
+			steps: []searchStep{
+				{at: 0, want: turn},
+				{at: 9 * time.Second, want: turn},
+				{at: 11 * time.Second, want: hover, state: SearchGaveUp},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			s, err := NewSearcher(test.config)
+			if err != nil {
+				t.Fatal(err)
+			}
+			start := time.Date(2019, 7, 1, 12, 0, 0, 0, time.UTC)
+			if test.seen != nil {
+				s.Seen(*test.seen, start)
+			}
+			for _, step := range test.steps {
+				v, state := s.Update(start.Add(step.at), step.height)
+				if !v.ApproxEqual(step.want) || state != step.state {
+					t.Errorf("at %v: got %v, %v, want %v, %v", step.at, v, state, step.want, step.state)
+				}
+			}
+		})
+	}
+}
+
+func TestSearcherReset(t *testing.T) {
+	s, err := NewSearcher(searchConfig(SearchYawSweep))
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := time.Date(2019, 7, 1, 12, 0, 0, 0, time.UTC)
+	s.Update(start, 1)
+	if _, state := s.Update(start.Add(100*time.Second), 1); state != SearchGaveUp {
+		t.Fatalf("got %v, want the search to give up", state)
+	}
+	s.Reset()
+	if v, state := s.Update(start.Add(200*time.Second), 1); state != Searching || v[3] != 0.5 {
+		t.Errorf("got %v, %v after a reset, want a new search", v, state)
+	}
+}
+
+func TestSearchConfigValidate(t *testing.T) {
+	if err := DefaultSearchConfig().Validate(); err != nil {
+		t.Errorf("default config: %v", err)
+	}
+	for _, behaviours := range [][]string{nil, {"spiral"}} {
+		c := DefaultSearchConfig()
+		c.Behaviours = behaviours
+		if err := c.Validate(); err == nil {
+			t.Errorf("behaviours %v are valid", behaviours)
+		}
+	}
+}
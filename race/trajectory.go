@@ -0,0 +1,258 @@
+package race
+
+import (
+	"math"
+	"sync"
+	"time"
+
+	"github.com/go-gl/mathgl/mgl32"
+	"tellobot/localization"
+	"tellobot/logging"
+)
+
+// waypoint is a position passed with a velocity, both in world coordinates
+type waypoint struct {
+	position mgl32.Vec3
+	velocity mgl32.Vec3 // m/s
+}
+
+// Trajectory is a smooth path through waypoints. Every segment is a cubic
+// Hermite curve in time, so position and velocity are continuous and gates
+// are passed along their normals.
+type Trajectory struct {
+	points    []waypoint
+	durations []time.Duration
+	duration  time.Duration
+}
+
+// PlanTrajectory returns a trajectory from the current position and velocity
+// through the gates. The last gate of the course is left by the exit
+// distance and the trajectory stops there, otherwise it ends in the last
+// gate at cruise speed.
+func PlanTrajectory(position mgl32.Vec3, velocity mgl32.Vec3, gates []*Gate, last bool, config TrajectoryConfig) *Trajectory {
+	t := &Trajectory{points: []waypoint{{position, velocity}}}
+	for _, g := range gates {
+		t.points = append(t.points, waypoint{g.Position, g.Normal().Mul(config.Speed)})
+	}
+	if last && len(gates) > 0 {
+		g := gates[len(gates)-1]
+		t.points = append(t.points, waypoint{g.Position.Add(g.Normal().Mul(config.Exit)), mgl32.Vec3{}})
+	}
+
+	for i := 1; i < len(t.points); i++ {
+		dist := t.points[i].position.Sub(t.points[i-1].position).Len()
+		seconds := math.Max(float64(dist/config.Speed), config.MinSegment.Seconds())
+		d := time.Duration(seconds * float64(time.Second))
+		t.durations = append(t.durations, d)
+		t.duration += d
+	}
+	return t
+}
+
+func (t *Trajectory) Duration() time.Duration {
+	return t.duration
+}
+
+// Sample returns the position and velocity at time at after the start, the
+// end point after the end
+func (t *Trajectory) Sample(at time.Duration) (position mgl32.Vec3, velocity mgl32.Vec3) {
+	if at < 0 {
+		at = 0
+	}
+	for i, d := range t.durations {
+		if at <= d {
+			return hermite(t.points[i], t.points[i+1], float32(d.Seconds()), float32(at.Seconds()/d.Seconds()))
+		}
+		at -= d
+	}
+	end := t.points[len(t.points)-1]
+	return end.position, end.velocity
+}
+
+// Points returns n positions evenly spaced in time, e.g. for drawing
+func (t *Trajectory) Points(n int) []mgl32.Vec3 {
+	points := make([]mgl32.Vec3, n)
+	for i := range points {
+		at := time.Duration(0)
+		if n > 1 {
+			at = t.duration * time.Duration(i) / time.Duration(n-1)
+		}
+		points[i], _ = t.Sample(at)
+	}
+	return points
+}
+
+// hermite evaluates the segment from a to b of duration d (s) at the fraction s
+func hermite(a waypoint, b waypoint, d float32, s float32) (mgl32.Vec3, mgl32.Vec3) {
+	s2, s3 := s*s, s*s*s
+	h00 := 2*s3 - 3*s2 + 1
+	h10 := s3 - 2*s2 + s
+	h01 := -2*s3 + 3*s2
+	h11 := s3 - s2
+	position := a.position.Mul(h00).
+		Add(a.velocity.Mul(h10 * d)).
+		Add(b.position.Mul(h01)).
+		Add(b.velocity.Mul(h11 * d))
+
+	// derivatives by s, divided by the duration for m/s
+	dh00 := 6*s2 - 6*s
+	dh10 := 3*s2 - 4*s + 1
+	dh01 := -6*s2 + 6*s
+	dh11 := 3*s2 - 2*s
+	velocity := a.position.Mul(dh00 / d).
+		Add(a.velocity.Mul(dh10)).
+		Add(b.position.Mul(dh01 / d)).
+		Add(b.velocity.Mul(dh11))
+	return position, velocity
+}
+
+// Planner flies the course along trajectories through the next gates. It
+// replans when a gate is passed and periodically, so corrections of the pose
+// do not accumulate, starting every new trajectory with the velocity of the
+// old one.
+type Planner struct {
+	mutex  sync.Mutex
+	config TrajectoryConfig
+	radius float32 // m, of the gates
+	course *Course
+
+	next       int // index of the next gate in the course
+	trajectory *Trajectory
+	start      time.Time
+	last       *mgl32.Vec3 // position of the last update
+}
+
+func NewPlanner(course *Course, config Config) *Planner {
+	return &Planner{course: course, config: config.Trajectory, radius: config.RingRadius}
+}
+
+// Reset starts the course again at the first gate
+func (p *Planner) Reset() {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	p.next = 0
+	p.trajectory = nil
+	p.last = nil
+}
+
+// Next returns the next gate, false once all gates are passed
+func (p *Planner) Next() (*Gate, bool) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	if p.next >= len(p.course.Gates) {
+		return nil, false
+	}
+	return p.course.Gates[p.next], true
+}
+
+// Trajectory returns the current trajectory, nil before the first update
+func (p *Planner) Trajectory() *Trajectory {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	return p.trajectory
+}
+
+// Update returns the velocity (-1.0 to 1.0, right, up, forward, clockwise)
+// that keeps the drone on the trajectory. done is true once the drone has
+// reached the end of the course.
+func (p *Planner) Update(pose *localization.Pose, now time.Time) (v mgl32.Vec4, done bool) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	c := p.config
+	gates := p.course.Gates
+
+	// a gate is passed once the drone crossed the plane of the gate within
+	// the ring, flying around it does not count
+	passed := false
+	for p.last != nil && p.next < len(gates) {
+		g := gates[p.next]
+		through, ok := crossing(g, *p.last, pose.Position)
+		if !ok {
+			break
+		}
+		if through.Sub(g.Position).Len() > p.radius {
+			log.Warn("gate missed", logging.Fields{"gate": g.Number, "offset": through.Sub(g.Position).Len()})
+			break
+		}
+		log.Info("gate passed", logging.Fields{"gate": g.Number})
+		p.next++
+		passed = true
+	}
+	position := pose.Position
+	p.last = &position
+
+	if p.trajectory == nil || passed || now.Sub(p.start) > c.Replan {
+		if p.next >= len(gates) && p.trajectory == nil {
+			return mgl32.Vec4{}, true
+		}
+		var velocity mgl32.Vec3
+		if p.trajectory != nil {
+			_, velocity = p.trajectory.Sample(now.Sub(p.start))
+		}
+		if p.next < len(gates) {
+			end := p.next + c.Gates
+			if end > len(gates) {
+				end = len(gates)
+			}
+			p.trajectory = PlanTrajectory(pose.Position, velocity, gates[p.next:end], end == len(gates), c)
+			p.start = now
+		}
+	}
+
+	at := now.Sub(p.start)
+	ref, refVelocity := p.trajectory.Sample(at + c.Lookahead)
+	if p.next >= len(gates) && at >= p.trajectory.Duration() && pose.Position.Sub(ref).Len() < c.Tolerance {
+		return mgl32.Vec4{}, true
+	}
+
+	// velocity feed forward plus a correction towards the reference
+	world := refVelocity.Add(ref.Sub(pose.Position).Mul(c.PositionGain))
+	rel := pose.Rotation.Transpose().Mul3x1(world)
+
+	// face along the trajectory, or through the next gate when slow
+	yaw := yawOf(refVelocity)
+	if refVelocity.Len() < c.Speed/4 && p.next < len(gates) {
+		yaw = yawOf(gates[p.next].Normal())
+	}
+	yawErr := wrapAngle(yaw - yawOf(pose.Rotation.Mul3x1(mgl32.Vec3{0, 0, 1})))
+
+	limit := func(x float32) float32 {
+		return mgl32.Clamp(x, -c.MaxCommand, c.MaxCommand)
+	}
+	// drone coordinates have y pointing down, velocities up
+	v = mgl32.Vec4{
+		limit(rel[0] / c.MaxSpeed),
+		limit(-rel[1] / c.MaxSpeed),
+		limit(rel[2] / c.MaxSpeed),
+		limit(yawErr * c.YawGain),
+	}
+	log.Debug("trajectory", logging.Fields{"t": at.Seconds(), "ref": ref, "position": pose.Position, "velocity": v})
+	return v, false
+}
+
+// crossing returns where the way from a to b crosses the plane of the gate
+// in the direction of its normal, ok is false if it does not
+func crossing(g *Gate, a mgl32.Vec3, b mgl32.Vec3) (through mgl32.Vec3, ok bool) {
+	n := g.Normal()
+	da := a.Sub(g.Position).Dot(n)
+	db := b.Sub(g.Position).Dot(n)
+	if da >= 0 || db < 0 {
+		return mgl32.Vec3{}, false
+	}
+	return a.Add(b.Sub(a).Mul(da / (da - db))), true
+}
+
+// yawOf returns the clockwise angle of a direction from the world z axis
+func yawOf(dir mgl32.Vec3) float32 {
+	return float32(math.Atan2(float64(dir[0]), float64(dir[2])))
+}
+
+func wrapAngle(a float32) float32 {
+	for a > math.Pi {
+		a -= 2 * math.Pi
+	}
+	for a < -math.Pi {
+		a += 2 * math.Pi
+	}
+	return a
+}
@@ -0,0 +1,72 @@
+package race
+
+import (
+	"testing"
+	"time"
+
+	"github.com/go-gl/mathgl/mgl32"
+	"tellobot/localization"
+)
+
+func TestPlannerPassesGates(t *testing.T) {
+	course := &Course{Gates: []*Gate{
+		{Number: 1, Position: mgl32.Vec3{0, 0, 2}},
+		{Number: 2, Position: mgl32.Vec3{0, 0, 4}},
+	}}
+	radius := DefaultConfig().RingRadius
+
+	tests := []struct {
+		name string
+		path []mgl32.Vec3 // positions of the updates
+		next int          // number of the next gate, 0 when all are passed
+	}{
+		{"in front", []mgl32.Vec3{{0, 0, 0}, {0, 0, 1.9}}, 1},
+		{"through the center", []mgl32.Vec3{{0, 0, 1.9}, {0, 0, 2.1}}, 2},
+		{"through the edge", []mgl32.Vec3{{0, 0, 1.9}, {radius * 0.9, 0, 2.1}}, 2},
+		{"beside the ring", []mgl32.Vec3{{0, 0, 1.9}, {2 * radius, 0, 2.1}}, 1},
+		{"above the ring", []mgl32.Vec3{{0, -2 * radius, 1.9}, {0, -2 * radius, 2.1}}, 1},
+		{"backwards", []mgl32.Vec3{{0, 0, 2.1}, {0, 0, 1.9}}, 1},
+		{"starting behind the gate", []mgl32.Vec3{{0, 0, 3}}, 1},
+		{"through both", []mgl32.Vec3{{0, 0, 1.9}, {0, 0, 2.1}, {0, 0, 3.9}, {0, 0, 4.1}}, 0},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			p := NewPlanner(course, DefaultConfig())
+			now := time.Now()
+			for _, position := range test.path {
+				pose := localization.Pose{Position: position, Rotation: mgl32.Ident3()}
+				p.Update(&pose, now)
+				now = now.Add(100 * time.Millisecond)
+			}
+
+			next := 0
+			if g, ok := p.Next(); ok {
+				next = g.Number
+			}
+			if next != test.next {
+				t.Errorf("next gate %d, want %d", next, test.next)
+			}
+		})
+	}
+}
+
+func TestPlannerReset(t *testing.T) {
+	course := &Course{Gates: []*Gate{{Number: 1, Position: mgl32.Vec3{0, 0, 2}}}}
+	p := NewPlanner(course, DefaultConfig())
+	now := time.Now()
+	for _, z := range []float32{1.9, 2.1} {
+		pose := localization.Pose{Position: mgl32.Vec3{0, 0, z}, Rotation: mgl32.Ident3()}
+		p.Update(&pose, now)
+	}
+	if _, ok := p.Next(); ok {
+		t.Fatal("gate not passed")
+	}
+
+	// the position before the reset is no crossing
+	p.Reset()
+	pose := localization.Pose{Position: mgl32.Vec3{0, 0, 2.2}, Rotation: mgl32.Ident3()}
+	p.Update(&pose, now)
+	if g, ok := p.Next(); !ok || g.Number != 1 {
+		t.Errorf("next gate %v after a reset", g)
+	}
+}
@@ -0,0 +1,181 @@
+package tracking
+
+import (
+	"sync"
+	"time"
+
+	"github.com/go-gl/mathgl/mgl32"
+	"gobot.io/x/gobot/platforms/dji/tello"
+	"tellobot/logging"
+	"tellobot/tuning"
+)
+
+// altitude hold gains tuned while flying, Configure seeds them from the
+// config
+var (
+	altitudeGain     = tuning.Default.Float("tracking.altitude.gain", "climb velocity per m of error", 0.8, 0, 5)
+	altitudeIntegral = tuning.Default.Float("tracking.altitude.integral", "climb velocity per m*s of error", 0.2, 0, 5)
+	altitudeMaxClimb = tuning.Default.Float("tracking.altitude.maxClimb", "climb velocity limit", 0.5, 0, 1)
+	altitudeDeadband = tuning.Default.Float("tracking.altitude.deadband", "height error that is ignored (m)", 0.05, 0, 1)
+)
+
+// tuned returns the config with the current values of the tuning parameters
+func (c AltitudeConfig) tuned() AltitudeConfig {
+	c.Gain = altitudeGain.Float32()
+	c.Integral = altitudeIntegral.Float32()
+	c.MaxClimb = altitudeMaxClimb.Float32()
+	c.Deadband = altitudeDeadband.Float32()
+	return c
+}
+
+// VerticalDriver is the part of a drone the altitude hold controls. Both
+// drone.Drone and tello.Driver implement it.
+type VerticalDriver interface {
+	Up(val int) error
+	Down(val int) error
+}
+
+// AltitudeHold keeps the drone at a target height using the telemetry height
+// and optionally a range sensor. It only drives the vertical axis so it can
+// run alongside any other controller, and stays out of the way while a
+// higher-priority controller has claimed the axis with Yield.
+type AltitudeHold struct {
+	mutex  sync.Mutex
+	config AltitudeConfig
+
+	target     float32 // m
+	enabled    bool
+	yieldUntil time.Time
+
+	rangeSource func() (float32, bool)
+
+	integral float32
+	lastTime time.Time
+}
+
+func NewAltitudeHold(config AltitudeConfig) *AltitudeHold {
+	return &AltitudeHold{
+		config:  config,
+		target:  config.Target,
+		enabled: true,
+	}
+}
+
+// SetTarget sets the height to hold in m
+func (a *AltitudeHold) SetTarget(height float32) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	a.target = height
+	a.integral = 0
+}
+
+func (a *AltitudeHold) Target() float32 {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	return a.target
+}
+
+// SetRangeSource sets an optional height sensor, e.g. the time of flight
+// sensor, which is preferred over the barometric telemetry height while it
+// reports a valid reading in m
+func (a *AltitudeHold) SetRangeSource(fn func() (float32, bool)) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	a.rangeSource = fn
+}
+
+func (a *AltitudeHold) Enable() {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	a.enabled = true
+}
+
+func (a *AltitudeHold) Disable() {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	a.enabled = false
+	a.integral = 0
+}
+
+// Yield hands the vertical axis to another controller for the given time.
+// Call it every control cycle for as long as the axis is needed.
+func (a *AltitudeHold) Yield(d time.Duration) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	a.yieldUntil = time.Now().Add(d)
+	a.integral = 0
+}
+
+// Active returns true if the altitude hold currently owns the vertical axis
+func (a *AltitudeHold) Active() bool {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	return a.active(time.Now())
+}
+
+func (a *AltitudeHold) active(now time.Time) bool {
+	return a.enabled && !now.Before(a.yieldUntil)
+}
+
+// Velocity returns the vertical velocity (-1.0 to 1.0, up positive) needed to
+// reach the target height. ok is false if the hold is inactive or there is no
+// height reading.
+func (a *AltitudeHold) Velocity(fd *tello.FlightData) (v float32, ok bool) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	now := time.Now()
+	dt := float32(0)
+	if !a.lastTime.IsZero() {
+		dt = float32(now.Sub(a.lastTime).Seconds())
+	}
+	a.lastTime = now
+
+	if !a.active(now) {
+		return 0, false
+	}
+
+	height, ok := a.height(fd)
+	if !ok {
+		return 0, false
+	}
+
+	c := a.config.tuned()
+	err := a.target - height
+	if err > -c.Deadband && err < c.Deadband {
+		return 0, true
+	}
+
+	a.integral = mgl32.Clamp(a.integral+err*dt, -c.MaxWindup, c.MaxWindup)
+	v = c.Gain*err + c.Integral*a.integral
+
+	v = mgl32.Clamp(v, -c.MaxClimb, c.MaxClimb)
+	log.Debug("altitude", logging.Fields{"height": height, "target": a.target, "integral": a.integral, "velocity": v})
+	return v, true
+}
+
+// Update commands the vertical axis of d if the hold is active
+func (a *AltitudeHold) Update(fd *tello.FlightData, d VerticalDriver) {
+	v, ok := a.Velocity(fd)
+	if !ok {
+		return
+	}
+	if v >= 0 {
+		d.Up(int(v * 100))
+	} else {
+		d.Down(int(-v * 100))
+	}
+}
+
+func (a *AltitudeHold) height(fd *tello.FlightData) (float32, bool) {
+	if a.rangeSource != nil {
+		if h, ok := a.rangeSource(); ok {
+			return h, true
+		}
+	}
+	if fd == nil {
+		return 0, false
+	}
+	// telemetry height is in dm
+	return float32(fd.Height) / 10, true
+}
@@ -0,0 +1,214 @@
+package tracking
+
+import (
+	"encoding/json"
+	"fmt"
+	"image"
+	"io/ioutil"
+	"math"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"gocv.io/x/gocv"
+)
+
+// ColorProfile is a colour target stored under a name
+type ColorProfile struct {
+	HSV   HSVConfig `json:"hsv"`
+	Width float32   `json:"width"` // m, 0 if unknown, gives the range to the object
+}
+
+// ColorProfiles stores colour profiles as json files in a directory
+type ColorProfiles struct {
+	dir string
+}
+
+func NewColorProfiles(dir string) *ColorProfiles {
+	return &ColorProfiles{dir: dir}
+}
+
+func (p *ColorProfiles) Save(name string, profile ColorProfile) error {
+	filename, err := p.file(name)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(p.dir, 0755); err != nil {
+		return err
+	}
+	buf, err := json.MarshalIndent(profile, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(filename, buf, 0644)
+}
+
+func (p *ColorProfiles) Load(name string) (ColorProfile, error) {
+	var profile ColorProfile
+	filename, err := p.file(name)
+	if err != nil {
+		return profile, err
+	}
+	buf, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return profile, err
+	}
+	if err := json.Unmarshal(buf, &profile); err != nil {
+		return profile, fmt.Errorf("colour profile %s: %v", name, err)
+	}
+	return profile, nil
+}
+
+// Names lists the stored profiles
+func (p *ColorProfiles) Names() ([]string, error) {
+	files, err := filepath.Glob(filepath.Join(p.dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, len(files))
+	for i, f := range files {
+		names[i] = strings.TrimSuffix(filepath.Base(f), ".json")
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+func (p *ColorProfiles) file(name string) (string, error) {
+	if name == "" || strings.ContainsAny(name, `/\.`) {
+		return "", fmt.Errorf("invalid colour profile name %q", name)
+	}
+	return filepath.Join(p.dir, name+".json"), nil
+}
+
+// Handler serves the profiles of a colour target relative to where it is
+// mounted:
+//
+//	GET  /            names of the stored profiles
+//	POST /{name}      saves the current thresholds and width as a profile
+//	PUT  /{name}      loads a profile
+//	POST /autotune    {"x":, "y":, "w":, "h":} tunes the thresholds to the
+//	                  pixels of the rectangle in the next frame
+func (p *ColorProfiles) Handler(t *ColorTarget) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		name := strings.TrimPrefix(req.URL.Path, "/")
+		switch {
+		case name == "" && req.Method == http.MethodGet:
+			names, err := p.Names()
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+			writeJSON(w, names)
+
+		case name == "autotune" && req.Method == http.MethodPost:
+			var r struct{ X, Y, W, H int }
+			if err := json.NewDecoder(req.Body).Decode(&r); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			t.AutoTune(image.Rect(r.X, r.Y, r.X+r.W, r.Y+r.H))
+			w.WriteHeader(http.StatusAccepted)
+
+		case req.Method == http.MethodPost:
+			if err := p.Save(name, t.Profile()); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			writeJSON(w, t.Profile())
+
+		case req.Method == http.MethodPut:
+			profile, err := p.Load(name)
+			if err == nil {
+				err = t.SetProfile(profile)
+			}
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			writeJSON(w, profile)
+
+		default:
+			http.Error(w, "use GET to list, POST to save or PUT to load", http.StatusMethodNotAllowed)
+		}
+	})
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(v)
+}
+
+// AutoTuneHSV returns thresholds around the colours of a region of a BGR
+// image. The hue range is centered on the circular mean of the hues so reds
+// get a range wrapping around 180.
+func AutoTuneHSV(img gocv.Mat, region image.Rectangle) (HSVConfig, error) {
+	region = region.Intersect(image.Rect(0, 0, img.Cols(), img.Rows()))
+	if region.Empty() {
+		return HSVConfig{}, fmt.Errorf("empty auto tune region")
+	}
+	roi := img.Region(region)
+	defer roi.Close()
+	hsv := gocv.NewMat()
+	defer hsv.Close()
+	gocv.CvtColor(roi, &hsv, gocv.ColorBGRToHSV)
+
+	n := hsv.Rows() * hsv.Cols()
+	hues := make([]float64, 0, n)
+	sats := make([]float64, 0, n)
+	vals := make([]float64, 0, n)
+	var sin, cos float64
+	for row := 0; row < hsv.Rows(); row++ {
+		for col := 0; col < hsv.Cols(); col++ {
+			p := hsv.GetVecbAt(row, col)
+			if len(p) < 3 {
+				continue
+			}
+			h := float64(p[0])
+			a := h * math.Pi / 90
+			sin += math.Sin(a)
+			cos += math.Cos(a)
+			hues = append(hues, h)
+			sats = append(sats, float64(p[1]))
+			vals = append(vals, float64(p[2]))
+		}
+	}
+	if len(hues) == 0 {
+		return HSVConfig{}, fmt.Errorf("no pixels in the auto tune region")
+	}
+
+	// the hue spread is measured relative to the mean, so it does not
+	// matter on which side of 0 the hues of a red lie
+	mean := math.Atan2(sin, cos) * 90 / math.Pi
+	for i, h := range hues {
+		hues[i] = math.Remainder(h-mean, 180)
+	}
+
+	const hueMargin, margin = 5, 20
+	lo := mean + percentile(hues, 0.05) - hueMargin
+	hi := mean + percentile(hues, 0.95) + hueMargin
+	c := HSVConfig{
+		HMin: 0,
+		HMax: 180,
+		SMin: clamp(percentile(sats, 0.05)-margin, 0, 255),
+		SMax: clamp(percentile(sats, 0.95)+margin, 0, 255),
+		VMin: clamp(percentile(vals, 0.05)-margin, 0, 255),
+		VMax: clamp(percentile(vals, 0.95)+margin, 0, 255),
+	}
+	if hi-lo < 180 {
+		c.HMin = int(math.Mod(lo+180, 180))
+		c.HMax = int(math.Mod(hi+180, 180))
+	}
+	return c, nil
+}
+
+// percentile sorts the values and returns the one at the fraction p
+func percentile(values []float64, p float64) float64 {
+	sort.Float64s(values)
+	return values[int(p*float64(len(values)-1))]
+}
+
+func clamp(v float64, min int, max int) int {
+	return int(math.Max(float64(min), math.Min(float64(max), v)))
+}
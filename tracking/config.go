@@ -0,0 +1,265 @@
+package tracking
+
+import (
+	"fmt"
+	"time"
+
+	"tellobot/tuning"
+)
+
+// Config holds the controller gains, the defaults are what the controllers
+// were tuned with
+type Config struct {
+	HSV      HSVConfig      `yaml:"hsv"`
+	Altitude AltitudeConfig `yaml:"altitude"`
+	Follow   FollowConfig   `yaml:"follow"`
+	Tracker  TrackerConfig  `yaml:"tracker"`
+	Landing  LandingConfig  `yaml:"landing"`
+}
+
+// HSVConfig are the thresholds of FilterImageTuned. Hues go from 0 to 180,
+// a HMin above HMax wraps around 180, e.g. 170 to 10 for reds.
+type HSVConfig struct {
+	HMin int `yaml:"hmin" json:"hmin"`
+	HMax int `yaml:"hmax" json:"hmax"`
+	SMin int `yaml:"smin" json:"smin"`
+	SMax int `yaml:"smax" json:"smax"`
+	VMin int `yaml:"vmin" json:"vmin"`
+	VMax int `yaml:"vmax" json:"vmax"`
+}
+
+// Wraps returns whether the hue range wraps around 180
+func (c HSVConfig) Wraps() bool {
+	return c.HMin > c.HMax
+}
+
+// AltitudeConfig configures an AltitudeHold, Configure makes the gains the
+// defaults of the tuning parameters
+type AltitudeConfig struct {
+	Target    float32       `yaml:"target"`    // m
+	Gain      float32       `yaml:"gain"`      // velocity per m of error
+	Integral  float32       `yaml:"integral"`  // velocity per m*s of error
+	MaxClimb  float32       `yaml:"maxClimb"`  // velocity limit
+	Deadband  float32       `yaml:"deadband"`  // m
+	MaxWindup float32       `yaml:"maxWindup"` // m*s
+	Yield     time.Duration `yaml:"yield"`     // how long a ring controller keeps the axis
+}
+
+// FollowConfig configures a Follower, offsets are fractions of the frame and
+// velocities fractions of full power. Configure makes the distances, gains
+// and limits the defaults of the tuning parameters.
+type FollowConfig struct {
+	Distance          float32 `yaml:"distance"`          // m
+	DistanceTolerance float32 `yaml:"distanceTolerance"` // m
+	Deadband          float32 `yaml:"deadband"`          // offset and rotation that are ignored
+	MinConfidence     float32 `yaml:"minConfidence"`     // detections below it are not followed
+	YawGain           float32 `yaml:"yawGain"`           // velocity per offset
+	ClimbGain         float32 `yaml:"climbGain"`         // velocity per offset
+	ForwardGain       float32 `yaml:"forwardGain"`       // velocity per m
+	StrafeGain        float32 `yaml:"strafeGain"`        // velocity per unit of target rotation
+	MaxYaw            float32 `yaml:"maxYaw"`
+	MaxClimb          float32 `yaml:"maxClimb"`
+	MaxForward        float32 `yaml:"maxForward"`
+	MaxStrafe         float32 `yaml:"maxStrafe"`
+	Smoothing         int     `yaml:"smoothing"` // detections averaged, 0 or 1 is off
+}
+
+// TrackerConfig configures a MultiTracker
+type TrackerConfig struct {
+	MinIoU    float64 `yaml:"minIoU"`    // overlap needed to match a detection to a track
+	MinHits   int     `yaml:"minHits"`   // frames in a row before a track is confirmed
+	MaxMissed int     `yaml:"maxMissed"` // frames a track survives without detection
+	AutoLock  bool    `yaml:"autoLock"`  // lock on to the most confident track without a lock
+}
+
+// LandingConfig configures a PrecisionLanding, velocities are fractions of
+// full power
+type LandingConfig struct {
+	MarkerID       int           `yaml:"markerID"`       // -1 lands on any marker
+	MarkerSize     float32       `yaml:"markerSize"`     // m
+	Standoff       float32       `yaml:"standoff"`       // m, pad distance ahead when aligned, 0 for a downward camera
+	AlignTolerance float32       `yaml:"alignTolerance"` // m
+	StageHeight    float32       `yaml:"stageHeight"`    // m descended before aligning again
+	LandHeight     float32       `yaml:"landHeight"`     // m, lands from this height
+	MinHeight      float32       `yaml:"minHeight"`      // m, lands normally when the pad is lost below it
+	Gain           float32       `yaml:"gain"`           // velocity per m of offset
+	MaxSpeed       float32       `yaml:"maxSpeed"`
+	DescentSpeed   float32       `yaml:"descentSpeed"`
+	SearchYaw      float32       `yaml:"searchYaw"`     // turn velocity while searching
+	SearchTimeout  time.Duration `yaml:"searchTimeout"` // lands normally when the pad is not found
+	LostTimeout    time.Duration `yaml:"lostTimeout"`   // hovers this long when the pad is lost
+}
+
+func DefaultConfig() Config {
+	return Config{
+		HSV: HSVConfig{
+			HMin: 163,
+			HMax: 180,
+			SMin: 115,
+			SMax: 255,
+			VMin: 50,
+			VMax: 242,
+		},
+		Altitude: DefaultAltitudeConfig(),
+		Follow:   DefaultFollowConfig(),
+		Tracker:  DefaultTrackerConfig(),
+		Landing:  DefaultLandingConfig(),
+	}
+}
+
+func DefaultAltitudeConfig() AltitudeConfig {
+	return AltitudeConfig{
+		Target:    1.2,
+		Gain:      0.8,
+		Integral:  0.2,
+		MaxClimb:  0.5,
+		Deadband:  0.05,
+		MaxWindup: 1.0,
+		Yield:     500 * time.Millisecond,
+	}
+}
+
+func DefaultFollowConfig() FollowConfig {
+	return FollowConfig{
+		Distance:          1.5,
+		DistanceTolerance: 0.25,
+		Deadband:          0.1,
+		MinConfidence:     0.5,
+		YawGain:           1,
+		ClimbGain:         1,
+		ForwardGain:       0.4,
+		StrafeGain:        0.3,
+		MaxYaw:            0.5,
+		MaxClimb:          0.5,
+		MaxForward:        0.3,
+		MaxStrafe:         0.3,
+		Smoothing:         5,
+	}
+}
+
+func DefaultTrackerConfig() TrackerConfig {
+	return TrackerConfig{
+		MinIoU:    0.3,
+		MinHits:   3,
+		MaxMissed: 10,
+		AutoLock:  true,
+	}
+}
+
+func (c TrackerConfig) Validate() error {
+	if c.MinIoU <= 0 || c.MinIoU > 1 {
+		return fmt.Errorf("tracker min iou %v not in (0, 1]", c.MinIoU)
+	}
+	if c.MinHits < 1 || c.MaxMissed < 0 {
+		return fmt.Errorf("invalid tracker min hits %d or max missed %d", c.MinHits, c.MaxMissed)
+	}
+	return nil
+}
+
+func DefaultLandingConfig() LandingConfig {
+	return LandingConfig{
+		MarkerID:       0,
+		MarkerSize:     0.15,
+		Standoff:       0.5,
+		AlignTolerance: 0.1,
+		StageHeight:    0.3,
+		LandHeight:     0.4,
+		MinHeight:      0.6,
+		Gain:           0.5,
+		MaxSpeed:       0.2,
+		DescentSpeed:   0.2,
+		SearchYaw:      0.3,
+		SearchTimeout:  20 * time.Second,
+		LostTimeout:    time.Second,
+	}
+}
+
+func (c LandingConfig) Validate() error {
+	if c.MarkerSize <= 0 || c.Standoff < 0 || c.AlignTolerance <= 0 || c.StageHeight <= 0 {
+		return fmt.Errorf("invalid landing pad size %v, standoff %v, tolerance %v or stage %v", c.MarkerSize, c.Standoff, c.AlignTolerance, c.StageHeight)
+	}
+	if c.LandHeight < 0 || c.MinHeight < c.LandHeight {
+		return fmt.Errorf("landing min height %v below land height %v", c.MinHeight, c.LandHeight)
+	}
+	for _, v := range []float32{c.MaxSpeed, c.DescentSpeed, c.SearchYaw} {
+		if v < -1 || v > 1 {
+			return fmt.Errorf("landing velocity %v not in [-1, 1]", v)
+		}
+	}
+	return nil
+}
+
+func (c FollowConfig) Validate() error {
+	if c.Distance <= 0 || c.DistanceTolerance < 0 || c.Deadband < 0 {
+		return fmt.Errorf("invalid follow distance %v, tolerance %v or deadband %v", c.Distance, c.DistanceTolerance, c.Deadband)
+	}
+	if c.Smoothing < 0 {
+		return fmt.Errorf("negative follow smoothing %d", c.Smoothing)
+	}
+	for _, max := range []float32{c.MaxYaw, c.MaxClimb, c.MaxForward, c.MaxStrafe} {
+		if max < 0 || max > 1 {
+			return fmt.Errorf("follow velocity limit %v not in [0, 1]", max)
+		}
+	}
+	return nil
+}
+
+func (c Config) Validate() error {
+	for _, h := range []int{c.HSV.HMin, c.HSV.HMax} {
+		if h < 0 || h > 180 {
+			return fmt.Errorf("hue threshold %d not in [0, 180]", h)
+		}
+	}
+	for _, v := range []int{c.HSV.SMin, c.HSV.SMax, c.HSV.VMin, c.HSV.VMax} {
+		if v < 0 || v > 255 {
+			return fmt.Errorf("hsv threshold %d not in [0, 255]", v)
+		}
+	}
+	if c.Altitude.Target < 0 || c.Altitude.MaxClimb < 0 || c.Altitude.MaxClimb > 1 {
+		return fmt.Errorf("invalid altitude hold target %v or climb limit %v", c.Altitude.Target, c.Altitude.MaxClimb)
+	}
+	if err := c.Follow.Validate(); err != nil {
+		return err
+	}
+	if err := c.Tracker.Validate(); err != nil {
+		return err
+	}
+	return c.Landing.Validate()
+}
+
+// Configure makes the config the default of the tuning parameters, it fails
+// if a value is outside the range of its parameter
+func Configure(c Config) error {
+	values := []struct {
+		param *tuning.Param
+		value float64
+	}{
+		{hmin, float64(c.HSV.HMin)},
+		{hmax, float64(c.HSV.HMax)},
+		{smin, float64(c.HSV.SMin)},
+		{smax, float64(c.HSV.SMax)},
+		{vmin, float64(c.HSV.VMin)},
+		{vmax, float64(c.HSV.VMax)},
+		{followDistance, float64(c.Follow.Distance)},
+		{followTolerance, float64(c.Follow.DistanceTolerance)},
+		{followDeadband, float64(c.Follow.Deadband)},
+		{followYawGain, float64(c.Follow.YawGain)},
+		{followClimbGain, float64(c.Follow.ClimbGain)},
+		{followForwardGain, float64(c.Follow.ForwardGain)},
+		{followStrafeGain, float64(c.Follow.StrafeGain)},
+		{followMaxYaw, float64(c.Follow.MaxYaw)},
+		{followMaxClimb, float64(c.Follow.MaxClimb)},
+		{followMaxForward, float64(c.Follow.MaxForward)},
+		{followMaxStrafe, float64(c.Follow.MaxStrafe)},
+		{altitudeGain, float64(c.Altitude.Gain)},
+		{altitudeIntegral, float64(c.Altitude.Integral)},
+		{altitudeMaxClimb, float64(c.Altitude.MaxClimb)},
+		{altitudeDeadband, float64(c.Altitude.Deadband)},
+	}
+	for _, v := range values {
+		if err := v.param.SetDefault(v.value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,236 @@
+package tracking
+
+import (
+	"image"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/go-gl/mathgl/mgl32"
+)
+
+// detectionGap restarts the smoothing of detections, e.g. when the next ring
+// comes into view
+const detectionGap = 500 * time.Millisecond
+
+// Filter smooths a signal, every instance keeps its own history
+type Filter interface {
+	// Filter adds a sample taken at t and returns the filtered value
+	Filter(x float64, t time.Time) float64
+	// Reset forgets the history
+	Reset()
+}
+
+// MovingAverage is the mean of the last samples
+type MovingAverage struct {
+	values []float64
+	next   int
+	count  int
+	sum    float64
+}
+
+func NewMovingAverage(size int) *MovingAverage {
+	if size < 1 {
+		size = 1
+	}
+	return &MovingAverage{values: make([]float64, size)}
+}
+
+func (f *MovingAverage) Filter(x float64, t time.Time) float64 {
+	if f.count == len(f.values) {
+		f.sum -= f.values[f.next]
+	} else {
+		f.count++
+	}
+	f.values[f.next] = x
+	f.sum += x
+	f.next = (f.next + 1) % len(f.values)
+	return f.sum / float64(f.count)
+}
+
+func (f *MovingAverage) Reset() {
+	f.next, f.count, f.sum = 0, 0, 0
+}
+
+// Exponential weighs a new sample with alpha and the history with 1-alpha
+type Exponential struct {
+	alpha float64
+	value float64
+	ok    bool
+}
+
+func NewExponential(alpha float64) *Exponential {
+	return &Exponential{alpha: alpha}
+}
+
+func (f *Exponential) Filter(x float64, t time.Time) float64 {
+	if !f.ok {
+		f.value, f.ok = x, true
+	} else {
+		f.value += f.alpha * (x - f.value)
+	}
+	return f.value
+}
+
+func (f *Exponential) Reset() {
+	f.ok = false
+}
+
+// Median is the median of the last samples, it ignores single outliers
+type Median struct {
+	values []float64
+	next   int
+	count  int
+	sorted []float64
+}
+
+func NewMedian(size int) *Median {
+	if size < 1 {
+		size = 1
+	}
+	return &Median{values: make([]float64, size), sorted: make([]float64, 0, size)}
+}
+
+func (f *Median) Filter(x float64, t time.Time) float64 {
+	f.values[f.next] = x
+	f.next = (f.next + 1) % len(f.values)
+	if f.count < len(f.values) {
+		f.count++
+	}
+
+	f.sorted = append(f.sorted[:0], f.values[:f.count]...)
+	sort.Float64s(f.sorted)
+	n := len(f.sorted)
+	if n%2 == 1 {
+		return f.sorted[n/2]
+	}
+	return (f.sorted[n/2-1] + f.sorted[n/2]) / 2
+}
+
+func (f *Median) Reset() {
+	f.next, f.count = 0, 0
+}
+
+// OneEuro smooths slow movement strongly and follows fast movement with
+// little lag, see Casiez et al., "1€ Filter", CHI 2012. The cutoffs are in
+// Hz, beta is how much the cutoff rises with speed.
+type OneEuro struct {
+	minCutoff float64
+	beta      float64
+	dCutoff   float64
+
+	x, dx    float64
+	lastTime time.Time
+	ok       bool
+}
+
+func NewOneEuro(minCutoff float64, beta float64, dCutoff float64) *OneEuro {
+	return &OneEuro{minCutoff: minCutoff, beta: beta, dCutoff: dCutoff}
+}
+
+func (f *OneEuro) Filter(x float64, t time.Time) float64 {
+	if !f.ok {
+		f.x, f.dx, f.lastTime, f.ok = x, 0, t, true
+		return x
+	}
+	dt := t.Sub(f.lastTime).Seconds()
+	f.lastTime = t
+	if dt <= 0 {
+		return f.x
+	}
+
+	dx := (x - f.x) / dt
+	f.dx += smoothingFactor(dt, f.dCutoff) * (dx - f.dx)
+	cutoff := f.minCutoff + f.beta*math.Abs(f.dx)
+	f.x += smoothingFactor(dt, cutoff) * (x - f.x)
+	return f.x
+}
+
+func (f *OneEuro) Reset() {
+	f.ok = false
+}
+
+func smoothingFactor(dt float64, cutoff float64) float64 {
+	r := 2 * math.Pi * cutoff * dt
+	return r / (r + 1)
+}
+
+// VecFilter filters every component of a vector with its own filter
+type VecFilter struct {
+	filters []Filter
+}
+
+func NewVecFilter(size int, newFilter func() Filter) *VecFilter {
+	v := &VecFilter{filters: make([]Filter, size)}
+	for i := range v.filters {
+		v.filters[i] = newFilter()
+	}
+	return v
+}
+
+// Filter filters the components of x in place and returns it
+func (v *VecFilter) Filter(x []float64, t time.Time) []float64 {
+	for i := range x {
+		if i < len(v.filters) {
+			x[i] = v.filters[i].Filter(x[i], t)
+		}
+	}
+	return x
+}
+
+func (v *VecFilter) Vec2(x mgl32.Vec2, t time.Time) mgl32.Vec2 {
+	f := v.Filter([]float64{float64(x[0]), float64(x[1])}, t)
+	return mgl32.Vec2{float32(f[0]), float32(f[1])}
+}
+
+func (v *VecFilter) Vec3(x mgl32.Vec3, t time.Time) mgl32.Vec3 {
+	f := v.Filter([]float64{float64(x[0]), float64(x[1]), float64(x[2])}, t)
+	return mgl32.Vec3{float32(f[0]), float32(f[1]), float32(f[2])}
+}
+
+func (v *VecFilter) Reset() {
+	for _, f := range v.filters {
+		f.Reset()
+	}
+}
+
+// DetectionFilter smooths the box and pose of the detections of one target
+type DetectionFilter struct {
+	box  *VecFilter // min x, min y, max x, max y
+	pose *VecFilter // position and rotation
+
+	hasPose  bool
+	lastTime time.Time
+}
+
+func NewDetectionFilter(newFilter func() Filter) *DetectionFilter {
+	return &DetectionFilter{box: NewVecFilter(4, newFilter), pose: NewVecFilter(4, newFilter)}
+}
+
+// Filter adds a detection taken at t and returns it smoothed, the history is
+// forgotten after a gap or when the pose appears or disappears
+func (f *DetectionFilter) Filter(d Detection, t time.Time) Detection {
+	if t.Sub(f.lastTime) > detectionGap || d.HasPose != f.hasPose {
+		f.Reset()
+	}
+	f.lastTime, f.hasPose = t, d.HasPose
+
+	b := f.box.Filter([]float64{float64(d.Box.Min.X), float64(d.Box.Min.Y), float64(d.Box.Max.X), float64(d.Box.Max.Y)}, t)
+	d.Box = image.Rect(round(b[0]), round(b[1]), round(b[2]), round(b[3]))
+	if d.HasPose {
+		p := f.pose.Filter([]float64{float64(d.Position[0]), float64(d.Position[1]), float64(d.Position[2]), float64(d.Rotation)}, t)
+		d.Position = mgl32.Vec3{float32(p[0]), float32(p[1]), float32(p[2])}
+		d.Rotation = float32(p[3])
+	}
+	return d
+}
+
+func (f *DetectionFilter) Reset() {
+	f.box.Reset()
+	f.pose.Reset()
+	f.lastTime = time.Time{}
+}
+
+func round(x float64) int {
+	return int(math.Floor(x + 0.5))
+}
@@ -0,0 +1,134 @@
+package tracking
+
+import (
+	"image"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/go-gl/mathgl/mgl32"
+)
+
+// run feeds samples 100ms apart and returns the filtered values
+func run(f Filter, samples ...float64) []float64 {
+	t := time.Unix(0, 0)
+	var out []float64
+	for _, x := range samples {
+		out = append(out, f.Filter(x, t))
+		t = t.Add(100 * time.Millisecond)
+	}
+	return out
+}
+
+func closeTo(a []float64, b []float64) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if math.Abs(a[i]-b[i]) > 1e-9 {
+			return false
+		}
+	}
+	return true
+}
+
+func TestFilters(t *testing.T) {
+	tests := []struct {
+		name    string
+		filter  Filter
+		samples []float64
+		want    []float64
+	}{
+		{"moving average fills up", NewMovingAverage(3), []float64{3, 6, 9}, []float64{3, 4.5, 6}},
+		{"moving average drops old samples", NewMovingAverage(2), []float64{1, 3, 5, 7}, []float64{1, 2, 4, 6}},
+		{"moving average of one", NewMovingAverage(0), []float64{1, 5}, []float64{1, 5}},
+		{"median ignores an outlier", NewMedian(3), []float64{1, 100, 2, 3}, []float64{1, 50.5, 2, 3}},
+		{"median of even count", NewMedian(4), []float64{4, 1, 3, 2}, []float64{4, 2.5, 3, 2.5}},
+		{"exponential starts at the first sample", NewExponential(0.5), []float64{2, 4, 4}, []float64{2, 3, 3.5}},
+		{"exponential of one follows", NewExponential(1), []float64{2, 7}, []float64{2, 7}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := run(test.filter, test.samples...); !closeTo(got, test.want) {
+				t.Errorf("got %v, want %v", got, test.want)
+			}
+
+			// a reset forgets the history
+			test.filter.Reset()
+			if got := run(test.filter, 42); got[0] != 42 {
+				t.Errorf("got %v after a reset", got[0])
+			}
+		})
+	}
+}
+
+func TestOneEuro(t *testing.T) {
+	f := NewOneEuro(1, 0, 1)
+	if got := run(f, 5); got[0] != 5 {
+		t.Fatalf("first sample %v", got[0])
+	}
+
+	// with beta 0 it is a low pass of 1Hz
+	f.Reset()
+	got := run(f, 0, 1)
+	alpha := smoothingFactor(0.1, 1)
+	if math.Abs(got[1]-alpha) > 1e-9 {
+		t.Errorf("got %v, want %v", got[1], alpha)
+	}
+
+	// beta raises the cutoff with speed, fast movement lags less
+	slow := run(NewOneEuro(1, 0, 1), 0, 10, 20, 30)
+	fast := run(NewOneEuro(1, 1, 1), 0, 10, 20, 30)
+	if 30-fast[3] >= 30-slow[3] {
+		t.Errorf("lag %v with beta, %v without", 30-fast[3], 30-slow[3])
+	}
+
+	// samples without time passing keep the value
+	f.Reset()
+	now := time.Now()
+	f.Filter(1, now)
+	if x := f.Filter(100, now); x != 1 {
+		t.Errorf("got %v for a sample at the same time", x)
+	}
+}
+
+func TestDetectionFilter(t *testing.T) {
+	f := NewDetectionFilter(func() Filter { return NewMovingAverage(2) })
+	now := time.Now()
+
+	d := f.Filter(Detection{Box: image.Rect(0, 0, 10, 10), HasPose: true, Position: mgl32.Vec3{0, 0, 1}, Rotation: 0.2}, now)
+	if d.Box != image.Rect(0, 0, 10, 10) || d.Position.Z() != 1 {
+		t.Fatalf("first detection %+v", d)
+	}
+	now = now.Add(30 * time.Millisecond)
+	d = f.Filter(Detection{Box: image.Rect(10, 20, 20, 30), HasPose: true, Position: mgl32.Vec3{0, 0, 2}, Rotation: 0.4}, now)
+	if d.Box != image.Rect(5, 10, 15, 20) || d.Position.Z() != 1.5 || math.Abs(float64(d.Rotation-0.3)) > 1e-6 {
+		t.Errorf("second detection %+v", d)
+	}
+
+	// a target that was gone for a while starts over
+	now = now.Add(time.Second)
+	d = f.Filter(Detection{Box: image.Rect(100, 100, 110, 110)}, now)
+	if d.Box != image.Rect(100, 100, 110, 110) {
+		t.Errorf("after a gap %v", d.Box)
+	}
+}
+
+func TestFollowerSmoothing(t *testing.T) {
+	c := DefaultFollowConfig()
+	c.Smoothing = 2
+	f := NewFollower(c)
+	defer followDeadband.Set(followDeadband.Get())
+	followDeadband.Set(0)
+
+	// a single jump of the target is halved
+	left := Detection{Box: image.Rect(0, 45, 10, 55), Confidence: 1}
+	right := Detection{Box: image.Rect(90, 45, 100, 55), Confidence: 1}
+	f.Update(left, 100, 100)
+	smoothed, _ := f.Update(right, 100, 100)
+	f.Unlock()
+	direct, _ := f.Update(right, 100, 100)
+	if smoothed[3] >= direct[3] || smoothed[3] != 0 {
+		t.Errorf("yaw %v smoothed, %v direct", smoothed[3], direct[3])
+	}
+}
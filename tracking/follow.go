@@ -0,0 +1,204 @@
+package tracking
+
+import (
+	"image"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/go-gl/mathgl/mgl32"
+	"gocv.io/x/gocv"
+	"tellobot/drone"
+	"tellobot/logging"
+	"tellobot/tuning"
+)
+
+// follow gains tuned while flying, Configure seeds them from the config
+var (
+	followDistance    = tuning.Default.Float("tracking.follow.distance", "distance kept to targets with a pose (m)", 1.5, 0.1, 5)
+	followTolerance   = tuning.Default.Float("tracking.follow.distanceTolerance", "distance error that is ignored (m)", 0.25, 0, 2)
+	followDeadband    = tuning.Default.Float("tracking.follow.deadband", "offset and rotation that are ignored", 0.1, 0, 1)
+	followYawGain     = tuning.Default.Float("tracking.follow.yawGain", "yaw velocity per offset", 1, 0, 5)
+	followClimbGain   = tuning.Default.Float("tracking.follow.climbGain", "climb velocity per offset", 1, 0, 5)
+	followForwardGain = tuning.Default.Float("tracking.follow.forwardGain", "forward velocity per m", 0.4, 0, 5)
+	followStrafeGain  = tuning.Default.Float("tracking.follow.strafeGain", "strafe velocity per unit of rotation", 0.3, 0, 5)
+	followMaxYaw      = tuning.Default.Float("tracking.follow.maxYaw", "yaw velocity limit", 0.5, 0, 1)
+	followMaxClimb    = tuning.Default.Float("tracking.follow.maxClimb", "climb velocity limit", 0.5, 0, 1)
+	followMaxForward  = tuning.Default.Float("tracking.follow.maxForward", "forward velocity limit", 0.3, 0, 1)
+	followMaxStrafe   = tuning.Default.Float("tracking.follow.maxStrafe", "strafe velocity limit", 0.3, 0, 1)
+)
+
+// tuned returns the config with the current values of the tuning parameters
+func (c FollowConfig) tuned() FollowConfig {
+	c.Distance = followDistance.Float32()
+	c.DistanceTolerance = followTolerance.Float32()
+	c.Deadband = followDeadband.Float32()
+	c.YawGain = followYawGain.Float32()
+	c.ClimbGain = followClimbGain.Float32()
+	c.ForwardGain = followForwardGain.Float32()
+	c.StrafeGain = followStrafeGain.Float32()
+	c.MaxYaw = followMaxYaw.Float32()
+	c.MaxClimb = followMaxClimb.Float32()
+	c.MaxForward = followMaxForward.Float32()
+	c.MaxStrafe = followMaxStrafe.Float32()
+	return c
+}
+
+// Detection is where a target was seen in a frame
+type Detection struct {
+	Box        image.Rectangle // image coordinates
+	Confidence float32         // 0..1
+
+	// Position in m in drone coordinates (x right, y down, z forward) and
+	// the rotation of the target around the vertical axis, 0 when it faces
+	// the drone. Only set by targets that can estimate their pose.
+	HasPose  bool
+	Position mgl32.Vec3
+	Rotation float32
+}
+
+// Center returns the middle of the bounding box
+func (d Detection) Center() image.Point {
+	return image.Pt((d.Box.Min.X+d.Box.Max.X)/2, (d.Box.Min.Y+d.Box.Max.Y)/2)
+}
+
+// Size returns the diagonal of the bounding box in pixels
+func (d Detection) Size() float64 {
+	return math.Hypot(float64(d.Box.Dx()), float64(d.Box.Dy()))
+}
+
+// Target finds the object to follow in a frame
+type Target interface {
+	Detect(frame *gocv.Mat) (Detection, bool)
+}
+
+// TargetFunc adapts a detection function to a Target
+type TargetFunc func(frame *gocv.Mat) (Detection, bool)
+
+func (f TargetFunc) Detect(frame *gocv.Mat) (Detection, bool) {
+	return f(frame)
+}
+
+// Follower keeps a target in the middle of the frame at a distance. Targets
+// with a pose are kept at the configured distance, targets without one at
+// the size they had when the follower was locked on to them. Detections are
+// smoothed so single noisy frames do not jerk the drone around. The gains
+// are the tuning parameters, so they can be changed while following.
+type Follower struct {
+	mutex   sync.Mutex
+	config  FollowConfig
+	refSize float64          // pixels, 0 when not locked
+	filter  *DetectionFilter // nil without smoothing
+}
+
+func NewFollower(config FollowConfig) *Follower {
+	f := &Follower{config: config}
+	if config.Smoothing > 1 {
+		f.SetFilter(func() Filter { return NewMovingAverage(config.Smoothing) })
+	}
+	return f
+}
+
+// SetFilter smooths the detections with newFilter instead of the configured
+// moving average, nil turns the smoothing off
+func (f *Follower) SetFilter(newFilter func() Filter) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	f.filter = nil
+	if newFilter != nil {
+		f.filter = NewDetectionFilter(newFilter)
+	}
+}
+
+// Lock keeps the distance of the detection for targets without a pose
+func (f *Follower) Lock(d Detection) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	f.refSize = d.Size()
+	f.reset()
+}
+
+// Unlock stops keeping the distance of targets without a pose
+func (f *Follower) Unlock() {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	f.refSize = 0
+	f.reset()
+}
+
+func (f *Follower) reset() {
+	if f.filter != nil {
+		f.filter.Reset()
+	}
+}
+
+// Update returns the velocity towards the target in a W x H frame and the
+// axes it controls, no axes when the detection is not confident enough
+func (f *Follower) Update(d Detection, W int, H int) (mgl32.Vec4, drone.AxisMask) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	c := f.config.tuned()
+	updates.Inc()
+
+	var v mgl32.Vec4
+	if d.Confidence < c.MinConfidence {
+		return v, 0
+	}
+	if f.filter != nil {
+		d = f.filter.Filter(d, time.Now())
+	}
+	axes := drone.AxisUp | drone.AxisClockwise
+
+	center := d.Center()
+	dx := float32(center.X-W/2) / float32(W)
+	dy := float32(center.Y-H/2) / float32(H)
+	if outside(dx, c.Deadband) {
+		v[3] = mgl32.Clamp(dx*c.YawGain, -c.MaxYaw, c.MaxYaw)
+	}
+	if outside(dy, c.Deadband) {
+		v[1] = mgl32.Clamp(-dy*c.ClimbGain, -c.MaxClimb, c.MaxClimb)
+	}
+
+	// the distance error in m, for targets without a pose the size relative
+	// to the locked size gives it as a fraction of the configured distance
+	var e float32
+	switch {
+	case d.HasPose:
+		e = d.Position.Z() - c.Distance
+		if outside(d.Rotation, c.Deadband) {
+			v[0] = mgl32.Clamp(d.Rotation*c.StrafeGain, -c.MaxStrafe, c.MaxStrafe)
+		}
+		axes |= drone.AxisForward | drone.AxisRight
+	case f.refSize > 0 && d.Size() > 0:
+		e = float32(f.refSize/d.Size()-1) * c.Distance
+		axes |= drone.AxisForward
+	}
+	if outside(e, c.DistanceTolerance) {
+		v[2] = mgl32.Clamp(e*c.ForwardGain, -c.MaxForward, c.MaxForward)
+	}
+
+	log.Debug("follow", logging.Fields{"dx": dx, "dy": dy, "distanceError": e, "rotation": d.Rotation, "velocity": v})
+	return v, axes
+}
+
+// Drive sets the velocity of a command source towards the target and
+// releases the source when the target was not found
+func (f *Follower) Drive(s *drone.Source, d Detection, found bool, W int, H int) {
+	if !found {
+		f.mutex.Lock()
+		f.reset()
+		f.mutex.Unlock()
+		s.Release()
+		return
+	}
+	v, axes := f.Update(d, W, H)
+	if axes == 0 {
+		s.Release()
+		return
+	}
+	s.SetAxes(v, axes)
+}
+
+func outside(v float32, band float32) bool {
+	return v > band || v < -band
+}
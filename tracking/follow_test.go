@@ -0,0 +1,46 @@
+package tracking
+
+import (
+	"image"
+	"testing"
+)
+
+func TestFollowerReadsTuning(t *testing.T) {
+	defer Configure(DefaultConfig())
+
+	c := DefaultConfig()
+	c.Follow.Smoothing = 0
+	c.Follow.Deadband = 0
+	c.Follow.YawGain = 1
+	c.Follow.MaxYaw = 1
+	if err := Configure(c); err != nil {
+		t.Fatal(err)
+	}
+	f := NewFollower(c.Follow)
+
+	// a quarter of the frame to the right
+	d := Detection{Box: image.Rect(70, 45, 80, 55), Confidence: 1}
+	if v, _ := f.Update(d, 100, 100); v[3] != 0.25 {
+		t.Fatalf("yaw %v, want 0.25", v[3])
+	}
+
+	// the follower picks up changed gains without a restart
+	followYawGain.Set(2)
+	if v, _ := f.Update(d, 100, 100); v[3] != 0.5 {
+		t.Errorf("yaw %v after doubling the gain, want 0.5", v[3])
+	}
+	followDeadband.Set(0.3)
+	if v, _ := f.Update(d, 100, 100); v[3] != 0 {
+		t.Errorf("yaw %v inside the deadband", v[3])
+	}
+}
+
+func TestConfigureRejectsOutOfRange(t *testing.T) {
+	defer Configure(DefaultConfig())
+
+	c := DefaultConfig()
+	c.Altitude.MaxClimb = 2
+	if err := Configure(c); err == nil {
+		t.Error("climb limit of 2 accepted")
+	}
+}
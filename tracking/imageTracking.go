@@ -1,47 +1,88 @@
 package tracking
 
 import (
-	"github.com/go-gl/mathgl/mgl32"
 	"gocv.io/x/gocv"
 	"image"
+	"tellobot/tuning"
 )
 
-type Avgcontainer struct {
-	index int
-	values []int
-}
+// HSV thresholds used by FilterImageTuned, tune them with track-color. OpenCV
+// hues go from 0 to 180.
+var (
+	hmin = tuning.Default.Float("tracking.hsv.hmin", "hue lower bound", 163, 0, 180)
+	hmax = tuning.Default.Float("tracking.hsv.hmax", "hue upper bound", 180, 0, 180)
+	smin = tuning.Default.Float("tracking.hsv.smin", "saturation lower bound", 115, 0, 255)
+	smax = tuning.Default.Float("tracking.hsv.smax", "saturation upper bound", 255, 0, 255)
+	vmin = tuning.Default.Float("tracking.hsv.vmin", "value lower bound", 50, 0, 255)
+	vmax = tuning.Default.Float("tracking.hsv.vmax", "value upper bound", 242, 0, 255)
+)
 
-type AvgcontainerFloat struct {
-	index int
-	values []float32
+// FilterImageTuned filters with the HSV thresholds of the tuning registry
+func FilterImageTuned(img gocv.Mat, dest *gocv.Mat) {
+	FilterHSV(img, dest, TunedHSV())
 }
 
-var (
-	xavg = Avgcontainer{ 0, make([]int, 10)}
-	yavg = Avgcontainer{ 0, make([]int, 10)}
+// TunedHSV returns the HSV thresholds of the tuning registry
+func TunedHSV() HSVConfig {
+	return HSVConfig{hmin.Int(), hmax.Int(), smin.Int(), smax.Int(), vmin.Int(), vmax.Int()}
+}
 
-	xavgAruco = Avgcontainer{ 0, make([]int, 10)}
-	yavgAruco = Avgcontainer{ 0, make([]int, 10)}
-)
+// SetTunedHSV changes the HSV thresholds of the tuning registry, e.g. to a
+// colour profile
+func SetTunedHSV(c HSVConfig) error {
+	values := []struct {
+		param *tuning.Param
+		value int
+	}{
+		{hmin, c.HMin}, {hmax, c.HMax},
+		{smin, c.SMin}, {smax, c.SMax},
+		{vmin, c.VMin}, {vmax, c.VMax},
+	}
+	for _, v := range values {
+		if err := v.param.Set(float64(v.value)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
 
 func FilterImage(img gocv.Mat, dest *gocv.Mat, hmin int, hmax int, smin int, smax int, vmin int, vmax int) {
+	FilterHSV(img, dest, HSVConfig{hmin, hmax, smin, smax, vmin, vmax})
+}
+
+// FilterHSV keeps the pixels within the thresholds, hue ranges wrapping
+// around 180 are split in two
+func FilterHSV(img gocv.Mat, dest *gocv.Mat, c HSVConfig) {
 	gocv.CvtColor(img, dest, gocv.ColorBGRToHSV)
 
-	gocv.InRangeWithScalar(*dest,
+	if !c.Wraps() {
+		inRange(*dest, dest, c.HMin, c.HMax, c)
+		MorphOps(dest)
+		return
+	}
+
+	upper := gocv.NewMat()
+	defer upper.Close()
+	inRange(*dest, &upper, c.HMin, 180, c)
+	inRange(*dest, dest, 0, c.HMax, c)
+	gocv.BitwiseOr(*dest, upper, dest)
+
+	MorphOps(dest)
+}
+
+func inRange(hsv gocv.Mat, dest *gocv.Mat, hmin int, hmax int, c HSVConfig) {
+	gocv.InRangeWithScalar(hsv,
 		gocv.NewScalar(
 			float64(hmin),
-			float64(smin),
-			float64(vmin),
+			float64(c.SMin),
+			float64(c.VMin),
 			0),
 		gocv.NewScalar(
 			float64(hmax),
-			float64(smax),
-			float64(vmax),
+			float64(c.SMax),
+			float64(c.VMax),
 			0),
 		dest)
-
-	MorphOps(dest)
-
 }
 
 func MorphOps(thresh *gocv.Mat) {
@@ -55,81 +96,3 @@ func MorphOps(thresh *gocv.Mat) {
 	gocv.Dilate(*thresh, thresh, dilateElement)
 
 }
-
-func TrackFilteredObject(x *int, y *int, distance *float32, filteredImage gocv.Mat) {
-
-	contours := gocv.FindContours(filteredImage, gocv.RetrievalCComp, gocv.ChainApproxSimple)
-
-	refArea := float64(0)
-
-	tempx := 0
-	tempy := 0
-
-	for i:=0 ; i < len(contours); i++ {
-		contour := contours[i]
-		area := gocv.ContourArea(contour)
-		if area > refArea {
-
-			sumx := 0
-			sumy := 0
-
-			for j := 0; j < len(contour); j++ {
-				sumx += contour[j].X
-				sumy += contour[j].Y
-			}
-
-			tempx = sumx / len(contour)
-			tempy = sumy / len(contour)
-			refArea = area
-		}
-	}
-
-	*x = populateAndGetAverage(tempx, &xavg)
-	*y = populateAndGetAverage(tempy, &yavg)
-
-}
-
-func TrackAruco(corners [][]mgl32.Vec2) (int, int){
-
-	sumX := 0
-	sumY := 0
-
-	if len(corners) > 0 {
-
-		index := 0
-		for i := 0; i < len(corners[index]); i++ {
-
-			point := corners[index][i]
-			sumX += int(point.X())
-			sumY += int(point.Y())
-		}
-
-		sumX = sumX / len(corners[index])
-		sumY = sumY / len(corners[index])
-	}
-
-	return populateAndGetAverage(sumX, &xavgAruco), populateAndGetAverage(sumY, &yavgAruco)
-
-}
-
-func populateAndGetAverage(val int, avgcontainer *Avgcontainer) int {
-	avgcontainer.values[avgcontainer.index] = val
-
-	for i := avgcontainer.index; i < len(avgcontainer.values); i++ {
-		if avgcontainer.values[i] == 0 {
-			avgcontainer.values[i] = val
-		}
-	}
-
-	avgcontainer.index = (avgcontainer.index + 1) % len(avgcontainer.values)
-
-	sum := 0
-
-	for i := 0; i < len(avgcontainer.values); i++ {
-		sum += avgcontainer.values[i]
-	}
-
-	return sum / len(avgcontainer.values)
-}
-
-
@@ -0,0 +1,311 @@
+package tracking
+
+import (
+	"image"
+	"math"
+	"sort"
+	"sync"
+
+	"gocv.io/x/gocv"
+	"tellobot/logging"
+)
+
+// MultiTarget finds all candidates of a kind of target in a frame
+type MultiTarget interface {
+	DetectAll(frame *gocv.Mat) []Detection
+}
+
+// MultiTargetFunc adapts a detection function to a MultiTarget
+type MultiTargetFunc func(frame *gocv.Mat) []Detection
+
+func (f MultiTargetFunc) DetectAll(frame *gocv.Mat) []Detection {
+	return f(frame)
+}
+
+// Track is an object followed across frames
+type Track struct {
+	ID        int
+	Detection Detection // the last matched detection with the filtered box
+	Hits      int       // frames matched in a row
+	Missed    int       // frames since the last match
+}
+
+// MultiTracker gives detections stable ids across frames, SORT style: every
+// track predicts its box with a constant velocity kalman filter and is
+// matched to the detection overlapping it most. One track can be locked on
+// to, so a follower does not switch targets when a second one shows up.
+type MultiTracker struct {
+	mutex  sync.Mutex
+	config TrackerConfig
+	tracks []*track
+	nextID int
+	locked int // track id, 0 when none
+}
+
+func NewMultiTracker(config TrackerConfig) *MultiTracker {
+	return &MultiTracker{config: config, nextID: 1}
+}
+
+// Update matches the detections of a frame to the tracks and returns the
+// confirmed tracks seen in it
+func (m *MultiTracker) Update(detections []Detection) []Track {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	for _, t := range m.tracks {
+		t.predict()
+	}
+
+	// greedy matching by overlap is close enough to an optimal assignment
+	// for the few objects in view
+	type pair struct {
+		track, detection int
+		iou              float64
+	}
+	var pairs []pair
+	for i, t := range m.tracks {
+		for j, d := range detections {
+			if iou := IoU(t.box(), d.Box); iou >= m.config.MinIoU {
+				pairs = append(pairs, pair{i, j, iou})
+			}
+		}
+	}
+	sort.Slice(pairs, func(i, j int) bool { return pairs[i].iou > pairs[j].iou })
+
+	trackMatched := make([]bool, len(m.tracks))
+	detectionMatched := make([]bool, len(detections))
+	for _, p := range pairs {
+		if trackMatched[p.track] || detectionMatched[p.detection] {
+			continue
+		}
+		trackMatched[p.track] = true
+		detectionMatched[p.detection] = true
+		m.tracks[p.track].update(detections[p.detection])
+	}
+
+	for i, t := range m.tracks {
+		if !trackMatched[i] {
+			t.Hits = 0
+			t.Missed++
+		}
+	}
+	for j, d := range detections {
+		if !detectionMatched[j] {
+			m.tracks = append(m.tracks, newTrack(m.nextID, d))
+			m.nextID++
+		}
+	}
+
+	live := m.tracks[:0]
+	for _, t := range m.tracks {
+		if t.Missed > m.config.MaxMissed {
+			if t.ID == m.locked {
+				log.Info("target lost", logging.Fields{"id": t.ID})
+				m.locked = 0
+			}
+			continue
+		}
+		live = append(live, t)
+	}
+	m.tracks = live
+
+	var seen []Track
+	for _, t := range m.tracks {
+		if t.Missed == 0 && t.Hits >= m.config.MinHits {
+			seen = append(seen, t.Track)
+		}
+	}
+	return seen
+}
+
+// Tracks returns all tracks including unconfirmed and coasting ones
+func (m *MultiTracker) Tracks() []Track {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	tracks := make([]Track, len(m.tracks))
+	for i, t := range m.tracks {
+		tracks[i] = t.Track
+	}
+	return tracks
+}
+
+// Lock follows the track with the id, it returns false if there is none
+func (m *MultiTracker) Lock(id int) bool {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	for _, t := range m.tracks {
+		if t.ID == id {
+			m.locked = id
+			log.Info("target locked", logging.Fields{"id": id})
+			return true
+		}
+	}
+	return false
+}
+
+// LockNext locks on to the confirmed track with the next higher id, wrapping
+// around to the lowest
+func (m *MultiTracker) LockNext() (int, bool) {
+	m.mutex.Lock()
+	var ids []int
+	for _, t := range m.tracks {
+		if t.Hits >= m.config.MinHits {
+			ids = append(ids, t.ID)
+		}
+	}
+	locked := m.locked
+	m.mutex.Unlock()
+
+	if len(ids) == 0 {
+		return 0, false
+	}
+	sort.Ints(ids)
+	next := ids[0]
+	for _, id := range ids {
+		if id > locked {
+			next = id
+			break
+		}
+	}
+	return next, m.Lock(next)
+}
+
+func (m *MultiTracker) Unlock() {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.locked = 0
+}
+
+// Locked returns the id of the locked track, 0 if there is none
+func (m *MultiTracker) Locked() int {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	return m.locked
+}
+
+// Target follows the locked track of the candidates. Without a lock it
+// locks on to the most confident confirmed track if AutoLock is set.
+func (m *MultiTracker) Target(candidates MultiTarget) Target {
+	return TargetFunc(func(frame *gocv.Mat) (Detection, bool) {
+		seen := m.Update(candidates.DetectAll(frame))
+
+		locked := m.Locked()
+		if locked == 0 && m.config.AutoLock && len(seen) > 0 {
+			best := seen[0]
+			for _, t := range seen[1:] {
+				if t.Detection.Confidence > best.Detection.Confidence {
+					best = t
+				}
+			}
+			m.Lock(best.ID)
+			locked = best.ID
+		}
+
+		for _, t := range seen {
+			if t.ID == locked {
+				return t.Detection, true
+			}
+		}
+		return Detection{}, false
+	})
+}
+
+// IoU returns the intersection over union of two boxes
+func IoU(a image.Rectangle, b image.Rectangle) float64 {
+	inter := a.Intersect(b)
+	if inter.Empty() {
+		return 0
+	}
+	i := float64(inter.Dx() * inter.Dy())
+	u := float64(a.Dx()*a.Dy()+b.Dx()*b.Dy()) - i
+	return i / u
+}
+
+// track filters the box center and area with a kalman filter each, the
+// aspect ratio is taken from the last detection
+type track struct {
+	Track
+	cx, cy, area kalman
+	aspect       float64 // width / height
+}
+
+func newTrack(id int, d Detection) *track {
+	c := d.Center()
+	w, h := float64(d.Box.Dx()), float64(d.Box.Dy())
+	return &track{
+		Track:  Track{ID: id, Detection: d, Hits: 1},
+		cx:     newKalman(float64(c.X), 0.01, 1),
+		cy:     newKalman(float64(c.Y), 0.01, 1),
+		area:   newKalman(w*h, 0.0001, 10),
+		aspect: w / h,
+	}
+}
+
+func (t *track) predict() {
+	// the area must not shrink below zero
+	if t.area.x+t.area.v <= 0 {
+		t.area.v = 0
+	}
+	t.cx.predict()
+	t.cy.predict()
+	t.area.predict()
+	t.Detection.Box = t.box()
+}
+
+func (t *track) update(d Detection) {
+	c := d.Center()
+	w, h := float64(d.Box.Dx()), float64(d.Box.Dy())
+	t.cx.update(float64(c.X))
+	t.cy.update(float64(c.Y))
+	t.area.update(w * h)
+	t.aspect = w / h
+
+	t.Detection = d
+	t.Detection.Box = t.box()
+	t.Hits++
+	t.Missed = 0
+}
+
+func (t *track) box() image.Rectangle {
+	area := math.Max(t.area.x, 1)
+	w := math.Sqrt(area * t.aspect)
+	h := area / w
+	return image.Rect(
+		int(t.cx.x-w/2), int(t.cy.x-h/2),
+		int(t.cx.x+w/2), int(t.cy.x+h/2))
+}
+
+// kalman estimates a value and its velocity per frame from noisy
+// measurements of the value
+type kalman struct {
+	x, v          float64 // state
+	p00, p01, p11 float64 // covariance
+	q, r          float64 // process and measurement noise
+}
+
+func newKalman(z float64, q float64, r float64) kalman {
+	// the velocity is unknown at first
+	return kalman{x: z, p00: 10, p11: 1000, q: q, r: r}
+}
+
+func (k *kalman) predict() {
+	k.x += k.v
+	k.p00 += 2*k.p01 + k.p11 + k.q
+	k.p01 += k.p11
+	k.p11 += k.q
+}
+
+func (k *kalman) update(z float64) {
+	s := k.p00 + k.r
+	k0 := k.p00 / s
+	k1 := k.p01 / s
+	y := z - k.x
+	k.x += k0 * y
+	k.v += k1 * y
+
+	k.p11 -= k1 * k.p01
+	k.p01 -= k0 * k.p01
+	k.p00 -= k0 * k.p00
+}
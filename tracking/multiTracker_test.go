@@ -0,0 +1,219 @@
+package tracking
+
+import (
+	"image"
+	"testing"
+
+	"gocv.io/x/gocv"
+)
+
+// box returns a detection of a 40x40 box centered at x, y
+func box(x int, y int) Detection {
+	return Detection{Box: image.Rect(x-20, y-20, x+20, y+20), Confidence: 1}
+}
+
+// ids returns the ids of the tracks in order
+func ids(tracks []Track) []int {
+	var ids []int
+	for _, t := range tracks {
+		ids = append(ids, t.ID)
+	}
+	return ids
+}
+
+// trackAt returns the id of the track whose box contains the point, 0 if none
+func trackAt(tracks []Track, x int, y int) int {
+	for _, t := range tracks {
+		if image.Pt(x, y).In(t.Detection.Box) {
+			return t.ID
+		}
+	}
+	return 0
+}
+
+func TestIoU(t *testing.T) {
+	tests := []struct {
+		a, b image.Rectangle
+		want float64
+	}{
+		{image.Rect(0, 0, 10, 10), image.Rect(0, 0, 10, 10), 1},
+		{image.Rect(0, 0, 10, 10), image.Rect(20, 0, 30, 10), 0},
+		{image.Rect(0, 0, 10, 10), image.Rect(10, 0, 20, 10), 0},
+		{image.Rect(0, 0, 10, 10), image.Rect(5, 0, 15, 10), 50.0 / 150},
+		{image.Rect(0, 0, 10, 10), image.Rect(0, 0, 5, 5), 0.25},
+	}
+	for _, test := range tests {
+		if got := IoU(test.a, test.b); got != test.want {
+			t.Errorf("IoU(%v, %v) = %v, want %v", test.a, test.b, got, test.want)
+		}
+	}
+}
+
+func TestMultiTrackerMinHits(t *testing.T) {
+	c := DefaultTrackerConfig()
+	c.MinHits = 3
+	m := NewMultiTracker(c)
+
+	// a track is only reported once it was seen in MinHits frames in a row
+	for frame := 1; frame <= 4; frame++ {
+		seen := m.Update([]Detection{box(100, 100)})
+		if confirmed := len(seen) == 1; confirmed != (frame >= c.MinHits) {
+			t.Errorf("frame %d: seen %v", frame, ids(seen))
+		}
+		if len(m.Tracks()) != 1 {
+			t.Fatalf("frame %d: %d tracks, want 1", frame, len(m.Tracks()))
+		}
+	}
+
+	// a miss starts the count again
+	m.Update(nil)
+	if seen := m.Update([]Detection{box(100, 100)}); len(seen) != 0 {
+		t.Errorf("confirmed %v right after a miss", ids(seen))
+	}
+	if _, ok := m.LockNext(); ok {
+		t.Error("locked an unconfirmed track")
+	}
+}
+
+func TestMultiTrackerGreedyMatch(t *testing.T) {
+	c := DefaultTrackerConfig()
+	c.MinHits = 1
+	m := NewMultiTracker(c)
+	first := m.Update([]Detection{box(100, 100), box(300, 100)})
+	if len(first) != 2 {
+		t.Fatalf("seen %v, want 2 tracks", ids(first))
+	}
+	left, right := trackAt(first, 100, 100), trackAt(first, 300, 100)
+
+	// both detections overlap the left track, the closer one takes it and the
+	// other starts a new track while the right track coasts
+	seen := m.Update([]Detection{box(110, 100), box(130, 100)})
+	if id := trackAt(seen, 110, 100); id != left {
+		t.Errorf("best match went to track %d, want %d", id, left)
+	}
+	if id := trackAt(seen, 140, 100); id == left || id == right || id == 0 {
+		t.Errorf("second detection went to track %d, want a new one", id)
+	}
+	if n := len(m.Tracks()); n != 3 {
+		t.Errorf("%d tracks, want the missed right one to coast", n)
+	}
+}
+
+func TestMultiTrackerCrossing(t *testing.T) {
+	c := DefaultTrackerConfig()
+	c.MinHits = 1
+	m := NewMultiTracker(c)
+
+	// two boxes pass each other with a small vertical offset, the velocity
+	// of the tracks keeps them apart while they overlap
+	var a, b int
+	for frame := 0; frame <= 20; frame++ {
+		xa, xb := 50+10*frame, 250-10*frame
+		seen := m.Update([]Detection{box(xa, 100), box(xb, 110)})
+		if frame == 0 {
+			a, b = trackAt(seen, xa, 100), trackAt(seen, xb, 110)
+			continue
+		}
+		if xa == xb {
+			// both boxes contain the point in the middle
+			continue
+		}
+		if id := trackAt(seen, xa-15, 100-15); id != a {
+			t.Fatalf("frame %d: left to right box is track %d, want %d", frame, id, a)
+		}
+		if id := trackAt(seen, xb+15, 110+15); id != b {
+			t.Fatalf("frame %d: right to left box is track %d, want %d", frame, id, b)
+		}
+	}
+}
+
+func TestMultiTrackerDropOut(t *testing.T) {
+	c := DefaultTrackerConfig()
+	c.MinHits = 1
+	c.MaxMissed = 3
+	m := NewMultiTracker(c)
+
+	seen := m.Update([]Detection{box(100, 100)})
+	id := seen[0].ID
+	if !m.Lock(id) {
+		t.Fatal("lock failed")
+	}
+
+	// a drop out up to MaxMissed frames keeps the id and the lock
+	for i := 0; i < c.MaxMissed; i++ {
+		if seen := m.Update(nil); len(seen) != 0 {
+			t.Fatalf("missed track seen: %v", ids(seen))
+		}
+	}
+	seen = m.Update([]Detection{box(100, 100)})
+	if len(seen) != 1 || seen[0].ID != id || m.Locked() != id {
+		t.Fatalf("after a short drop out seen %v locked %d, want %d", ids(seen), m.Locked(), id)
+	}
+
+	// a longer one drops the track and the lock
+	for i := 0; i <= c.MaxMissed; i++ {
+		m.Update(nil)
+	}
+	if len(m.Tracks()) != 0 || m.Locked() != 0 {
+		t.Errorf("after a long drop out tracks %v locked %d", ids(m.Tracks()), m.Locked())
+	}
+	seen = m.Update([]Detection{box(100, 100)})
+	if len(seen) != 1 || seen[0].ID == id {
+		t.Errorf("seen %v after a long drop out, want a new id", ids(seen))
+	}
+}
+
+func TestMultiTrackerLockNext(t *testing.T) {
+	c := DefaultTrackerConfig()
+	c.MinHits = 1
+	m := NewMultiTracker(c)
+	if _, ok := m.LockNext(); ok {
+		t.Fatal("locked without tracks")
+	}
+
+	m.Update([]Detection{box(100, 100), box(200, 100), box(300, 100)})
+	all := ids(m.Tracks())
+
+	// cycles through the ids in order and wraps around
+	for _, want := range []int{all[0], all[1], all[2], all[0]} {
+		id, ok := m.LockNext()
+		if !ok || id != want || m.Locked() != want {
+			t.Errorf("locked %d, want %d", id, want)
+		}
+	}
+
+	m.Unlock()
+	if m.Locked() != 0 {
+		t.Error("still locked")
+	}
+	if m.Lock(42) {
+		t.Error("locked an unknown id")
+	}
+}
+
+func TestMultiTrackerTarget(t *testing.T) {
+	c := DefaultTrackerConfig()
+	c.MinHits = 1
+	c.AutoLock = true
+	m := NewMultiTracker(c)
+
+	weak, strong := box(100, 100), box(300, 100)
+	weak.Confidence = 0.5
+	candidates := MultiTargetFunc(func(frame *gocv.Mat) []Detection {
+		return []Detection{weak, strong}
+	})
+	target := m.Target(candidates)
+
+	// without a lock the most confident track is followed
+	d, ok := target.Detect(nil)
+	if !ok || d.Confidence != 1 {
+		t.Fatalf("followed %+v, want the confident one", d)
+	}
+
+	// a more confident newcomer does not take the lock
+	weak.Confidence = 1
+	strong.Confidence = 0.5
+	if d, ok := target.Detect(nil); !ok || d.Center().X != 300 {
+		t.Errorf("followed %+v, want the locked one", d)
+	}
+}
@@ -0,0 +1,193 @@
+package tracking
+
+import (
+	"sync"
+	"time"
+
+	"github.com/go-gl/mathgl/mgl32"
+	"gobot.io/x/gobot/platforms/dji/tello"
+	"tellobot/drone"
+	"tellobot/logging"
+)
+
+// LandingState is the phase of a precision landing
+type LandingState string
+
+const (
+	LandingIdle      LandingState = "idle"
+	LandingSearch    LandingState = "search"   // turning until the pad is seen
+	LandingAlign     LandingState = "align"    // holding height until over the pad
+	LandingDescend   LandingState = "descend"  // down to the next stage while aligned
+	LandingDone      LandingState = "landed"   // landed on the pad
+	LandingFallback  LandingState = "fallback" // landed where the drone was
+	landingUnchanged LandingState = ""
+)
+
+// PrecisionLanding lands on a marker pad. It searches for the pad, aligns
+// over it with the marker pose and descends in stages, correcting the
+// position before every stage. If the pad is lost below the minimum height
+// or not found in time it lands normally.
+//
+// With the forward camera the pad can not be seen straight below, so the
+// drone aligns with the pad at the configured standoff ahead of it.
+type PrecisionLanding struct {
+	mutex  sync.Mutex
+	config LandingConfig
+
+	state       LandingState
+	stateTime   time.Time
+	stageHeight float32 // m, the height the current stage descends to
+	lastSeen    time.Time
+}
+
+func NewPrecisionLanding(config LandingConfig) *PrecisionLanding {
+	return &PrecisionLanding{config: config, state: LandingIdle}
+}
+
+// Start begins the search for the pad
+func (l *PrecisionLanding) Start() {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	l.setState(LandingSearch)
+	l.stageHeight = 0
+}
+
+// Stop cancels the landing
+func (l *PrecisionLanding) Stop() {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	l.setState(LandingIdle)
+}
+
+func (l *PrecisionLanding) State() LandingState {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	return l.state
+}
+
+// Active returns true while the landing controls the drone
+func (l *PrecisionLanding) Active() bool {
+	switch l.State() {
+	case LandingSearch, LandingAlign, LandingDescend:
+		return true
+	}
+	return false
+}
+
+// Update drives the source towards the pad detected in the latest frame,
+// landing itself is commanded on d
+func (l *PrecisionLanding) Update(pad Detection, found bool, fd *tello.FlightData, s *drone.Source, d drone.Drone) LandingState {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	if l.state == LandingIdle || l.state == LandingDone || l.state == LandingFallback {
+		return l.state
+	}
+
+	now := time.Now()
+	if found && pad.HasPose {
+		l.lastSeen = now
+	}
+	height, ok := landingHeight(pad, found, fd)
+	if !ok {
+		s.Release()
+		return l.state
+	}
+
+	next := landingUnchanged
+	switch l.state {
+	case LandingSearch:
+		next = l.search(now, found && pad.HasPose, s, d)
+	case LandingAlign, LandingDescend:
+		next = l.approach(now, pad, found && pad.HasPose, height, s, d)
+	}
+	if next != landingUnchanged {
+		log.Info("precision landing", logging.Fields{"state": next, "height": height})
+		l.setState(next)
+	}
+	return l.state
+}
+
+func (l *PrecisionLanding) search(now time.Time, seen bool, s *drone.Source, d drone.Drone) LandingState {
+	if seen {
+		return LandingAlign
+	}
+	if now.Sub(l.stateTime) > l.config.SearchTimeout {
+		s.Release()
+		d.Land()
+		return LandingFallback
+	}
+	s.SetAxes(mgl32.Vec4{0, 0, 0, l.config.SearchYaw}, drone.AllAxes)
+	return landingUnchanged
+}
+
+func (l *PrecisionLanding) approach(now time.Time, pad Detection, seen bool, height float32, s *drone.Source, d drone.Drone) LandingState {
+	c := l.config
+	if !seen {
+		if now.Sub(l.lastSeen) < c.LostTimeout {
+			s.SetAxes(mgl32.Vec4{}, drone.AllAxes)
+			return landingUnchanged
+		}
+		s.Release()
+		if height < c.MinHeight {
+			d.Land()
+			return LandingFallback
+		}
+		return LandingSearch
+	}
+
+	// the horizontal offset in drone coordinates to the point the pad is
+	// seen from at the standoff
+	ex := pad.Position.X()
+	ez := pad.Position.Z() - c.Standoff
+	var v mgl32.Vec4
+	v[0] = mgl32.Clamp(ex*c.Gain, -c.MaxSpeed, c.MaxSpeed)
+	v[2] = mgl32.Clamp(ez*c.Gain, -c.MaxSpeed, c.MaxSpeed)
+	aligned := ex*ex+ez*ez <= c.AlignTolerance*c.AlignTolerance
+
+	// only land over the pad, below the land height a misaligned drone keeps
+	// aligning without descending
+	if l.state == LandingDescend && aligned && height <= c.LandHeight {
+		s.Release()
+		d.Land()
+		return LandingDone
+	}
+
+	next := landingUnchanged
+	switch {
+	case l.state == LandingAlign && aligned:
+		// the next stage, not below the height the drone lands from
+		l.stageHeight = height - c.StageHeight
+		if l.stageHeight < c.LandHeight {
+			l.stageHeight = c.LandHeight
+		}
+		next = LandingDescend
+	case l.state == LandingDescend && (!aligned || height <= l.stageHeight):
+		next = LandingAlign
+	}
+	if l.state == LandingDescend && next == landingUnchanged {
+		v[1] = -c.DescentSpeed
+	}
+
+	s.SetAxes(v, drone.AllAxes)
+	log.Debug("landing", logging.Fields{"ex": ex, "ez": ez, "height": height, "stage": l.stageHeight, "velocity": v})
+	return next
+}
+
+func (l *PrecisionLanding) setState(state LandingState) {
+	l.state = state
+	l.stateTime = time.Now()
+}
+
+// landingHeight prefers the height above the pad from its pose over the
+// telemetry height
+func landingHeight(pad Detection, found bool, fd *tello.FlightData) (float32, bool) {
+	if found && pad.HasPose && pad.Position.Y() > 0 {
+		return pad.Position.Y(), true
+	}
+	if fd == nil {
+		return 0, false
+	}
+	// telemetry height is in dm
+	return float32(fd.Height) / 10, true
+}
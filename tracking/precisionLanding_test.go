@@ -0,0 +1,67 @@
+package tracking
+
+import (
+	"testing"
+
+	"github.com/go-gl/mathgl/mgl32"
+	"gobot.io/x/gobot/platforms/dji/tello"
+	"tellobot/drone"
+)
+
+// padAt returns a pad detection offset from the aligned position at the
+// standoff and the given height below the drone
+func padAt(c LandingConfig, offset float32, height float32) Detection {
+	return Detection{HasPose: true, Position: mgl32.Vec3{offset, height, c.Standoff}}
+}
+
+func TestPrecisionLandingOnlyLandsAligned(t *testing.T) {
+	c := DefaultLandingConfig()
+	d := drone.NewFake(drone.DefaultConfig())
+	d.TakeOff()
+	s := drone.NewCommandMux(d).AddSource(drone.SourceConfig{Name: "landing", Priority: drone.PriorityAutopilot})
+
+	l := NewPrecisionLanding(c)
+	l.Start()
+	steps := []struct {
+		pad  Detection
+		want LandingState
+	}{
+		{padAt(c, 0, 1), LandingAlign},
+		{padAt(c, 0, 1), LandingDescend},
+		// below the land height but off the pad
+		{padAt(c, 2*c.AlignTolerance, c.LandHeight/2), LandingAlign},
+		{padAt(c, 2*c.AlignTolerance, c.LandHeight/2), LandingAlign},
+		{padAt(c, 0, c.LandHeight/2), LandingDescend},
+		{padAt(c, 0, c.LandHeight/2), LandingDone},
+	}
+	for i, step := range steps {
+		state := l.Update(step.pad, true, d.FlightData(), s, d)
+		if state != step.want {
+			t.Fatalf("step %d: got %v, want %v", i, state, step.want)
+		}
+		if landed := !d.FlightData().EmSky; landed != (state == LandingDone) {
+			t.Fatalf("step %d: %v with landed %v", i, state, landed)
+		}
+	}
+}
+
+func TestPrecisionLandingFallback(t *testing.T) {
+	c := DefaultLandingConfig()
+	c.LostTimeout = 0
+	d := drone.NewFake(drone.DefaultConfig())
+	d.TakeOff()
+	s := drone.NewCommandMux(d).AddSource(drone.SourceConfig{Name: "landing", Priority: drone.PriorityAutopilot})
+
+	l := NewPrecisionLanding(c)
+	l.Start()
+	l.Update(padAt(c, 0, c.LandHeight/2), true, d.FlightData(), s, d)
+
+	// losing the pad below the minimum height lands where the drone is
+	low := &tello.FlightData{Height: int16(c.MinHeight*10) - 1}
+	if state := l.Update(Detection{}, false, low, s, d); state != LandingFallback {
+		t.Fatalf("got %v, want %v", state, LandingFallback)
+	}
+	if d.FlightData().EmSky {
+		t.Error("still flying after the fallback")
+	}
+}
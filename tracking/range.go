@@ -0,0 +1,41 @@
+package tracking
+
+import (
+	"image"
+
+	"github.com/go-gl/mathgl/mgl32"
+	"gocv.io/x/gocv"
+)
+
+// Range returns the distance in m of an object of a known width in m from
+// its width in pixels and the focal length of the camera matrix
+func Range(box image.Rectangle, width float32, camMatrix *gocv.Mat) (float32, bool) {
+	if box.Dx() <= 0 || camMatrix == nil || camMatrix.Empty() {
+		return 0, false
+	}
+	fx := float32(camMatrix.GetDoubleAt(0, 0))
+	if fx <= 0 {
+		return 0, false
+	}
+	return fx * width / float32(box.Dx()), true
+}
+
+// Locate returns the position of the center of an object of a known width
+// in camera coordinates, x right, y down and z forward
+func Locate(box image.Rectangle, width float32, camMatrix *gocv.Mat) (mgl32.Vec3, bool) {
+	z, ok := Range(box, width, camMatrix)
+	if !ok {
+		return mgl32.Vec3{}, false
+	}
+	fx := float32(camMatrix.GetDoubleAt(0, 0))
+	fy := float32(camMatrix.GetDoubleAt(1, 1))
+	cx := float32(camMatrix.GetDoubleAt(0, 2))
+	cy := float32(camMatrix.GetDoubleAt(1, 2))
+	if fy <= 0 {
+		return mgl32.Vec3{}, false
+	}
+
+	u := float32(box.Min.X+box.Max.X) / 2
+	v := float32(box.Min.Y+box.Max.Y) / 2
+	return mgl32.Vec3{(u - cx) * z / fx, (v - cy) * z / fy, z}, true
+}
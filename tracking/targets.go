@@ -0,0 +1,185 @@
+package tracking
+
+import (
+	"image"
+	"sync"
+
+	"github.com/go-gl/mathgl/mgl32"
+	"gocv.io/x/gocv"
+	"gocv.io/x/gocv/contrib"
+	"tellobot/drone"
+	"tellobot/logging"
+)
+
+// ColorTarget is the largest object within the tuned HSV thresholds. With
+// the width of the object and a camera its position is estimated too.
+type ColorTarget struct {
+	filtered gocv.Mat
+	minArea  float64 // pixels
+
+	mutex    sync.Mutex
+	width    float32 // m, 0 if unknown
+	camera   drone.Drone
+	autoTune *image.Rectangle
+}
+
+func NewColorTarget(minArea float64) *ColorTarget {
+	return &ColorTarget{filtered: gocv.NewMat(), minArea: minArea}
+}
+
+// Locate estimates the positions of the objects with the camera of the drone
+func (t *ColorTarget) Locate(d drone.Drone) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	t.camera = d
+}
+
+// Profile returns the current thresholds and object width
+func (t *ColorTarget) Profile() ColorProfile {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	return ColorProfile{HSV: TunedHSV(), Width: t.width}
+}
+
+// SetProfile makes the thresholds of the profile the tuned ones
+func (t *ColorTarget) SetProfile(p ColorProfile) error {
+	if err := SetTunedHSV(p.HSV); err != nil {
+		return err
+	}
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	t.width = p.Width
+	return nil
+}
+
+// AutoTune tunes the thresholds to the pixels within the region of the
+// next frame
+func (t *ColorTarget) AutoTune(region image.Rectangle) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	t.autoTune = &region
+}
+
+// Detect returns the largest object
+func (t *ColorTarget) Detect(frame *gocv.Mat) (Detection, bool) {
+	var largest Detection
+	for _, d := range t.DetectAll(frame) {
+		if d.Box.Dx()*d.Box.Dy() > largest.Box.Dx()*largest.Box.Dy() {
+			largest = d
+		}
+	}
+	return largest, !largest.Box.Empty()
+}
+
+// DetectAll returns all objects above the minimum area
+func (t *ColorTarget) DetectAll(frame *gocv.Mat) []Detection {
+	t.mutex.Lock()
+	region, width, camera := t.autoTune, t.width, t.camera
+	t.autoTune = nil
+	t.mutex.Unlock()
+
+	if region != nil {
+		if c, err := AutoTuneHSV(*frame, *region); err != nil {
+			log.Warn("auto tune", logging.Fields{"error": err})
+		} else if err := SetTunedHSV(c); err != nil {
+			log.Warn("auto tune", logging.Fields{"error": err})
+		} else {
+			log.Info("auto tune", logging.Fields{"hsv": c})
+		}
+	}
+
+	FilterImageTuned(*frame, &t.filtered)
+
+	var objects []Detection
+	for _, contour := range gocv.FindContours(t.filtered, gocv.RetrievalCComp, gocv.ChainApproxSimple) {
+		if gocv.ContourArea(contour) <= t.minArea {
+			continue
+		}
+		d := Detection{Box: gocv.BoundingRect(contour), Confidence: 1}
+		if width > 0 && camera != nil {
+			if pos, ok := Locate(d.Box, width, camera.CameraMatrix()); ok {
+				d.HasPose = true
+				d.Position = camera.CameraToDroneMatrix().Mul3x1(pos)
+			}
+		}
+		objects = append(objects, d)
+	}
+	return objects
+}
+
+// Filtered returns the thresholded image of the last detection
+func (t *ColorTarget) Filtered() *gocv.Mat {
+	return &t.filtered
+}
+
+func (t *ColorTarget) Close() error {
+	return t.filtered.Close()
+}
+
+// ArucoTarget is a single aruco marker, its pose is estimated with the
+// camera calibration of the drone
+type ArucoTarget struct {
+	dict       contrib.ArucoDictionary
+	id         int // -1 follows the first marker found
+	markerSize float32
+	drone      drone.Drone
+
+	corners [][]mgl32.Vec2
+	ids     []int
+}
+
+func NewArucoTarget(dict contrib.ArucoDictionary, id int, markerSize float32, d drone.Drone) *ArucoTarget {
+	return &ArucoTarget{dict: dict, id: id, markerSize: markerSize, drone: d}
+}
+
+// Detect returns the first marker
+func (t *ArucoTarget) Detect(frame *gocv.Mat) (Detection, bool) {
+	markers := t.DetectAll(frame)
+	if len(markers) == 0 {
+		return Detection{}, false
+	}
+	return markers[0], true
+}
+
+// DetectAll returns all markers with the id, or all of them
+func (t *ArucoTarget) DetectAll(frame *gocv.Mat) []Detection {
+	t.corners, t.ids = t.dict.DetectMarkers(frame)
+
+	var markers []Detection
+	for i, id := range t.ids {
+		if t.id >= 0 && id != t.id {
+			continue
+		}
+		rvecs, tvecs := contrib.EstimateMarkerPoses(t.corners[i:i+1], t.markerSize, t.drone.CameraMatrix(), t.drone.DistortionCoefficients())
+
+		// the marker normal points towards the camera when facing it
+		normal := contrib.Rodrigues(rvecs[0]).Mul3x1(mgl32.Vec3{0, 0, 1})
+		markers = append(markers, Detection{
+			Box:        BoundingBox(t.corners[i]),
+			Confidence: 1,
+			HasPose:    true,
+			Position:   t.drone.CameraToDroneMatrix().Mul3x1(tvecs[0]),
+			Rotation:   normal.X(),
+		})
+	}
+	return markers
+}
+
+// Markers returns all markers of the last detection, e.g. for drawing
+func (t *ArucoTarget) Markers() ([][]mgl32.Vec2, []int) {
+	return t.corners, t.ids
+}
+
+// BoundingBox returns the smallest rectangle containing the points
+func BoundingBox(points []mgl32.Vec2) image.Rectangle {
+	var r image.Rectangle
+	for i, p := range points {
+		pr := image.Rect(int(p.X()), int(p.Y()), int(p.X())+1, int(p.Y())+1)
+		if i == 0 {
+			r = pr
+		} else {
+			r = r.Union(pr)
+		}
+	}
+	return r
+}
@@ -0,0 +1,178 @@
+package tuning
+
+import (
+	"encoding/json"
+	"html/template"
+	"net/http"
+	"strings"
+)
+
+// Handler serves the tuning UI and its JSON api relative to where it is
+// mounted, e.g. with http.StripPrefix("/tuning", r.Handler()):
+//
+//	GET  /                 the UI
+//	GET  /params           all parameters
+//	POST /params           {"name": value, ...} sets parameters
+//	POST /reset            sets all parameters to their defaults
+//	GET  /profiles         names of the stored profiles
+//	POST /profiles/{name}  saves the current values as a profile
+//	PUT  /profiles/{name}  loads a profile
+//
+// The handler does not check who changes the parameters, mount it behind
+// groundcontrol.Guard.
+func (r *Registry) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", r.handleUI)
+	mux.HandleFunc("/params", r.handleParams)
+	mux.HandleFunc("/reset", r.handleReset)
+	mux.HandleFunc("/profiles", r.handleProfiles)
+	mux.HandleFunc("/profiles/", r.handleProfile)
+	return mux
+}
+
+func (r *Registry) handleUI(w http.ResponseWriter, req *http.Request) {
+	if req.URL.Path != "/" {
+		http.NotFound(w, req)
+		return
+	}
+	profiles, _ := r.Profiles()
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	uiTemplate.Execute(w, struct {
+		Params   []Param
+		Profiles []string
+	}{r.Params(), profiles})
+}
+
+func (r *Registry) handleParams(w http.ResponseWriter, req *http.Request) {
+	switch req.Method {
+	case http.MethodGet:
+	case http.MethodPost:
+		values := make(map[string]float64)
+		if err := json.NewDecoder(req.Body).Decode(&values); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		for name, v := range values {
+			if err := r.Set(name, v); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+		}
+	default:
+		http.Error(w, "use GET or POST", http.StatusMethodNotAllowed)
+		return
+	}
+	writeJSON(w, r.Params())
+}
+
+func (r *Registry) handleReset(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPost {
+		http.Error(w, "use POST", http.StatusMethodNotAllowed)
+		return
+	}
+	r.Reset()
+	writeJSON(w, r.Params())
+}
+
+func (r *Registry) handleProfiles(w http.ResponseWriter, req *http.Request) {
+	profiles, err := r.Profiles()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, profiles)
+}
+
+func (r *Registry) handleProfile(w http.ResponseWriter, req *http.Request) {
+	name := strings.TrimPrefix(req.URL.Path, "/profiles/")
+
+	var err error
+	switch req.Method {
+	case http.MethodPost:
+		err = r.SaveProfile(name)
+	case http.MethodPut:
+		err = r.LoadProfile(name)
+	default:
+		http.Error(w, "use POST to save or PUT to load", http.StatusMethodNotAllowed)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	writeJSON(w, r.Params())
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(v)
+}
+
+var uiTemplate = template.Must(template.New("ui").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>tellobot tuning</title>
+<style>
+body { font-family: sans-serif; margin: 2em; }
+td { padding: 0.2em 0.6em; }
+input[type=range] { width: 20em; }
+.description { color: #666; }
+</style>
+</head>
+<body>
+<h1>Tuning</h1>
+<table>
+{{range .Params}}
+<tr>
+	<td>{{.Name}}</td>
+	<td><input type="range" name="{{.Name}}" min="{{.Min}}" max="{{.Max}}" step="any" value="{{.Value}}"></td>
+	<td><output id="{{.Name}}">{{.Value}}</output></td>
+	<td class="description">{{.Description}} (default {{.Default}})</td>
+</tr>
+{{end}}
+</table>
+<p>
+	<button id="reset">Reset to defaults</button>
+</p>
+<h2>Profiles</h2>
+<p>
+	<select id="profiles">{{range .Profiles}}<option>{{.}}</option>{{end}}</select>
+	<button id="load">Load</button>
+	<input id="name" placeholder="profile name">
+	<button id="save">Save</button>
+</p>
+<script>
+function show(params) {
+	for (const p of params) {
+		document.querySelector('input[name="' + p.name + '"]').value = p.value;
+		document.getElementById(p.name).value = p.value;
+	}
+}
+// changes need the token of the page, if the server has one
+const token = new URLSearchParams(location.search).get('token');
+function send(method, url, body) {
+	const headers = {'Content-Type': 'application/json'};
+	if (token) {
+		headers['Authorization'] = 'Bearer ' + token;
+	}
+	fetch(url, {method: method, headers: headers, body: JSON.stringify(body || {})})
+		.then(r => r.ok ? r.json().then(show) : r.text().then(alert));
+}
+for (const input of document.querySelectorAll('input[type=range]')) {
+	input.addEventListener('input', () => {
+		document.getElementById(input.name).value = input.value;
+		send('POST', 'params', {[input.name]: parseFloat(input.value)});
+	});
+}
+document.getElementById('reset').onclick = () => send('POST', 'reset');
+document.getElementById('load').onclick = () =>
+	send('PUT', 'profiles/' + document.getElementById('profiles').value);
+document.getElementById('save').onclick = () => {
+	send('POST', 'profiles/' + document.getElementById('name').value);
+	setTimeout(() => location.reload(), 200);
+};
+</script>
+</body>
+</html>
+`))
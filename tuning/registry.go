@@ -0,0 +1,228 @@
+package tuning
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Default is the registry controllers register their parameters into
+var Default = NewRegistry("profiles")
+
+// Param is a numeric parameter that can be changed while flying
+type Param struct {
+	Name        string  `json:"name"`
+	Description string  `json:"description"`
+	Default     float64 `json:"default"`
+	Min         float64 `json:"min"`
+	Max         float64 `json:"max"`
+	Value       float64 `json:"value"`
+
+	registry *Registry
+}
+
+// Get returns the current value
+func (p *Param) Get() float64 {
+	p.registry.mutex.Lock()
+	defer p.registry.mutex.Unlock()
+	return p.Value
+}
+
+func (p *Param) Float32() float32 {
+	return float32(p.Get())
+}
+
+func (p *Param) Int() int {
+	return int(p.Get())
+}
+
+// Set changes the value, it must be within the parameter range
+func (p *Param) Set(v float64) error {
+	if v < p.Min || v > p.Max {
+		return fmt.Errorf("%s: %v not in [%v, %v]", p.Name, v, p.Min, p.Max)
+	}
+	p.registry.mutex.Lock()
+	defer p.registry.mutex.Unlock()
+	p.Value = v
+	return nil
+}
+
+// SetDefault changes the default and the current value, e.g. to the one
+// from a configuration file
+func (p *Param) SetDefault(v float64) error {
+	if err := p.Set(v); err != nil {
+		return err
+	}
+	p.registry.mutex.Lock()
+	defer p.registry.mutex.Unlock()
+	p.Default = v
+	return nil
+}
+
+// Registry holds parameters by name and stores sets of values as named
+// profiles in a directory
+type Registry struct {
+	mutex  sync.Mutex
+	params map[string]*Param
+	dir    string
+}
+
+func NewRegistry(profileDir string) *Registry {
+	return &Registry{
+		params: make(map[string]*Param),
+		dir:    profileDir,
+	}
+}
+
+// SetProfileDir changes where profiles are stored
+func (r *Registry) SetProfileDir(dir string) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.dir = dir
+}
+
+// Float registers a parameter. Registering a name twice returns the existing
+// parameter.
+func (r *Registry) Float(name string, description string, def float64, min float64, max float64) *Param {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if p, ok := r.params[name]; ok {
+		return p
+	}
+	p := &Param{
+		Name:        name,
+		Description: description,
+		Default:     def,
+		Min:         min,
+		Max:         max,
+		Value:       def,
+		registry:    r,
+	}
+	r.params[name] = p
+	return p
+}
+
+// Param returns the parameter with the given name
+func (r *Registry) Param(name string) (*Param, bool) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	p, ok := r.params[name]
+	return p, ok
+}
+
+// Params returns copies of all parameters sorted by name
+func (r *Registry) Params() []Param {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	params := make([]Param, 0, len(r.params))
+	for _, p := range r.params {
+		params = append(params, *p)
+	}
+	sort.Slice(params, func(i, j int) bool { return params[i].Name < params[j].Name })
+	return params
+}
+
+// Set changes a parameter by name
+func (r *Registry) Set(name string, v float64) error {
+	p, ok := r.Param(name)
+	if !ok {
+		return fmt.Errorf("unknown parameter %q", name)
+	}
+	return p.Set(v)
+}
+
+// Values returns all current values by name
+func (r *Registry) Values() map[string]float64 {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	values := make(map[string]float64, len(r.params))
+	for name, p := range r.params {
+		values[name] = p.Value
+	}
+	return values
+}
+
+// Reset sets all parameters back to their defaults
+func (r *Registry) Reset() {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	for _, p := range r.params {
+		p.Value = p.Default
+	}
+}
+
+// SaveProfile stores all current values under a name
+func (r *Registry) SaveProfile(name string) error {
+	filename, err := r.profileFile(name)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(filename), 0755); err != nil {
+		return err
+	}
+	buf, err := json.MarshalIndent(r.Values(), "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(filename, buf, 0644)
+}
+
+// LoadProfile sets the values stored under a name. Unknown parameters are
+// ignored so profiles survive parameters being removed.
+func (r *Registry) LoadProfile(name string) error {
+	filename, err := r.profileFile(name)
+	if err != nil {
+		return err
+	}
+	buf, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return err
+	}
+	values := make(map[string]float64)
+	if err := json.Unmarshal(buf, &values); err != nil {
+		return fmt.Errorf("profile %s: %v", name, err)
+	}
+	for n, v := range values {
+		if p, ok := r.Param(n); ok {
+			if err := p.Set(v); err != nil {
+				return fmt.Errorf("profile %s: %v", name, err)
+			}
+		}
+	}
+	return nil
+}
+
+// Profiles lists the names of all stored profiles
+func (r *Registry) Profiles() ([]string, error) {
+	r.mutex.Lock()
+	dir := r.dir
+	r.mutex.Unlock()
+
+	files, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, len(files))
+	for i, f := range files {
+		names[i] = strings.TrimSuffix(filepath.Base(f), ".json")
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+func (r *Registry) profileFile(name string) (string, error) {
+	if name == "" || strings.ContainsAny(name, `/\.`) {
+		return "", fmt.Errorf("invalid profile name %q", name)
+	}
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return filepath.Join(r.dir, name+".json"), nil
+}
@@ -0,0 +1,166 @@
+package tuning
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// newTestRegistry returns a registry with two parameters storing profiles in
+// a temporary directory, which is removed by the returned function
+func newTestRegistry(t *testing.T) (*Registry, func()) {
+	dir, err := ioutil.TempDir("", "tuning")
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := NewRegistry(filepath.Join(dir, "profiles"))
+	r.Float("gain", "Gain.", 0.5, 0, 1)
+	r.Float("speed", "Speed.", 0.3, 0, 1)
+	return r, func() { os.RemoveAll(dir) }
+}
+
+func TestParam(t *testing.T) {
+	r, remove := newTestRegistry(t)
+	defer remove()
+
+	p, _ := r.Param("gain")
+	if again := r.Float("gain", "Other.", 0.9, 0, 2); again != p || again.Get() != 0.5 {
+		t.Error("registering twice changed the parameter")
+	}
+	if err := p.Set(1.5); err == nil || p.Get() != 0.5 {
+		t.Errorf("out of range value set: %v", p.Get())
+	}
+	if err := r.Set("gain", 0.8); err != nil || p.Float32() != 0.8 {
+		t.Errorf("set: %v, %v", err, p.Get())
+	}
+	if err := r.Set("yaw", 0.8); err == nil {
+		t.Error("set an unknown parameter")
+	}
+
+	r.Reset()
+	if p.Get() != 0.5 {
+		t.Errorf("%v after a reset", p.Get())
+	}
+	if err := p.SetDefault(0.7); err != nil {
+		t.Fatal(err)
+	}
+	r.Reset()
+	if p.Get() != 0.7 {
+		t.Errorf("%v after a reset to the new default", p.Get())
+	}
+}
+
+func TestProfiles(t *testing.T) {
+	r, remove := newTestRegistry(t)
+	defer remove()
+
+	if names, err := r.Profiles(); err != nil || len(names) != 0 {
+		t.Fatalf("profiles %v, %v before saving", names, err)
+	}
+	r.Set("gain", 0.9)
+	if err := r.SaveProfile("windy"); err != nil {
+		t.Fatal(err)
+	}
+	r.Set("gain", 0.1)
+	if err := r.SaveProfile("calm"); err != nil {
+		t.Fatal(err)
+	}
+
+	r.Reset()
+	if err := r.LoadProfile("windy"); err != nil {
+		t.Fatal(err)
+	}
+	if want := map[string]float64{"gain": 0.9, "speed": 0.3}; !reflect.DeepEqual(r.Values(), want) {
+		t.Errorf("values %v, want %v", r.Values(), want)
+	}
+	if names, err := r.Profiles(); err != nil || !reflect.DeepEqual(names, []string{"calm", "windy"}) {
+		t.Errorf("profiles %v, %v", names, err)
+	}
+	if err := r.LoadProfile("stormy"); err == nil {
+		t.Error("loaded a missing profile")
+	}
+}
+
+func TestProfileNames(t *testing.T) {
+	r, remove := newTestRegistry(t)
+	defer remove()
+
+	for _, name := range []string{"", "..", "../tellobot", "a/b", `a\b`, "a.json", "/etc/passwd"} {
+		if err := r.SaveProfile(name); err == nil {
+			t.Errorf("saved %q", name)
+		}
+		if err := r.LoadProfile(name); err == nil || !strings.Contains(err.Error(), "invalid profile name") {
+			t.Errorf("load %q: %v", name, err)
+		}
+	}
+	if names, _ := r.Profiles(); len(names) != 0 {
+		t.Errorf("profiles %v after invalid names", names)
+	}
+}
+
+func TestLoadProfileValues(t *testing.T) {
+	r, remove := newTestRegistry(t)
+	defer remove()
+	r.SaveProfile("base")
+	filename, _ := r.profileFile("base")
+
+	// parameters that are gone are ignored, values out of range are not
+	ioutil.WriteFile(filename, []byte(`{"gain": 0.2, "removed": 4}`), 0644)
+	if err := r.LoadProfile("base"); err != nil {
+		t.Errorf("unknown parameter: %v", err)
+	}
+	ioutil.WriteFile(filename, []byte(`{"gain": 4}`), 0644)
+	if err := r.LoadProfile("base"); err == nil {
+		t.Error("loaded a value out of range")
+	}
+	ioutil.WriteFile(filename, []byte(`gain: 4`), 0644)
+	if err := r.LoadProfile("base"); err == nil {
+		t.Error("loaded a broken profile")
+	}
+}
+
+func TestHandler(t *testing.T) {
+	r, remove := newTestRegistry(t)
+	defer remove()
+	h := r.Handler()
+
+	tests := []struct {
+		method, path, body string
+		want               int
+	}{
+		{"GET", "/params", "", http.StatusOK},
+		{"POST", "/params", `{"gain": 0.6}`, http.StatusOK},
+		{"POST", "/params", `{"gain": 6}`, http.StatusBadRequest},
+		{"POST", "/params", `{"yaw": 0.6}`, http.StatusBadRequest},
+		{"DELETE", "/params", "", http.StatusMethodNotAllowed},
+		{"POST", "/profiles/windy", "{}", http.StatusOK},
+		{"PUT", "/profiles/windy", "{}", http.StatusOK},
+		{"POST", "/profiles/windy.json", "{}", http.StatusBadRequest},
+		{"POST", `/profiles/..\windy`, "{}", http.StatusBadRequest},
+		{"PUT", "/profiles/stormy", "{}", http.StatusBadRequest},
+		{"POST", "/reset", "{}", http.StatusOK},
+	}
+	for _, test := range tests {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest(test.method, test.path, strings.NewReader(test.body)))
+		if w.Code != test.want {
+			t.Errorf("%s %s: status %d, want %d: %s", test.method, test.path, w.Code, test.want, w.Body.String())
+		}
+	}
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("GET", "/params", nil))
+	var params []Param
+	if err := json.NewDecoder(w.Body).Decode(&params); err != nil {
+		t.Fatal(err)
+	}
+	if len(params) != 2 || params[0].Name != "gain" || params[0].Value != 0.5 {
+		t.Errorf("params %+v after the reset", params)
+	}
+}